@@ -0,0 +1,190 @@
+package cdp_helper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// consoleBufferSize is the number of recent console messages kept for the artifacts
+const consoleBufferSize = 100
+
+// ArtifactsError is returned instead of Err when ArtifactsOnError is enabled, Dir holds the artifacts
+type ArtifactsError struct {
+	Dir string
+	Err error
+}
+
+func (e *ArtifactsError) Error() string {
+	return fmt.Sprintf("%v (artifacts saved to %s)", e.Err, e.Dir)
+}
+
+func (e *ArtifactsError) Unwrap() error {
+	return e.Err
+}
+
+// artifactRecorder saves the state of a tab when a helper method fails
+type artifactRecorder struct {
+	mu      sync.Mutex
+	dir     string
+	console []string
+	cancel  context.CancelFunc
+}
+
+func (r *artifactRecorder) log(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.console = append(r.console, line)
+	if len(r.console) > consoleBufferSize {
+		r.console = r.console[len(r.console)-consoleBufferSize:]
+	}
+}
+
+func (r *artifactRecorder) recent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := make([]string, len(r.console))
+	copy(lines, r.console)
+	return lines
+}
+
+// ArtifactsOnError makes the helper methods of the current tab save a screenshot, the outer HTML,
+// the url and the recent console messages into a new timestamped folder under dir when they fail,
+// the error is then returned as an *ArtifactsError. Tabs created from h inherit the setting,
+// an empty dir disables it.
+func (h *CdpHelper) ArtifactsOnError(dir string) {
+	r := h.artifacts
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dir = dir
+	if dir == "" {
+		if r.cancel != nil {
+			r.cancel()
+			r.cancel = nil
+		}
+		r.console = nil
+		return
+	}
+	if r.cancel != nil {
+		return
+	}
+
+	var listenCtx context.Context
+	listenCtx, r.cancel = context.WithCancel(h.Current.Context)
+	chromedp.ListenTarget(listenCtx, func(ev any) {
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			args := make([]string, 0, len(ev.Args))
+			for _, arg := range ev.Args {
+				args = append(args, remoteObjectString(arg))
+			}
+			r.log(consoleLine(ev.Timestamp, string(ev.Type), strings.Join(args, " ")))
+		case *runtime.EventExceptionThrown:
+			text := ev.ExceptionDetails.Text
+			if ev.ExceptionDetails.Exception != nil {
+				text = remoteObjectString(ev.ExceptionDetails.Exception)
+			}
+			r.log(consoleLine(ev.Timestamp, "exception", text))
+		case *cdplog.EventEntryAdded:
+			r.log(consoleLine(ev.Entry.Timestamp, string(ev.Entry.Level), ev.Entry.Text+" "+ev.Entry.URL))
+		}
+	})
+}
+
+func consoleLine(timestamp *runtime.Timestamp, level string, text string) string {
+	t := time.Now()
+	if timestamp != nil {
+		t = timestamp.Time()
+	}
+	return fmt.Sprintf("%s [%s] %s", t.Format("15:04:05.000"), level, strings.TrimSpace(text))
+}
+
+// remoteObjectString formats a console argument the way devtools roughly does
+func remoteObjectString(obj *runtime.RemoteObject) string {
+	if obj.Value != nil {
+		var value any
+		if err := json.Unmarshal(obj.Value, &value); err == nil {
+			return fmt.Sprint(value)
+		}
+		return string(obj.Value)
+	}
+	if obj.UnserializableValue != "" {
+		return string(obj.UnserializableValue)
+	}
+	if obj.Description != "" {
+		return obj.Description
+	}
+	return string(obj.Type)
+}
+
+// withArtifacts saves the artifacts of the current tab and wraps err when ArtifactsOnError is enabled
+func (h *CdpHelper) withArtifacts(err error) error {
+	if err == nil {
+		return nil
+	}
+	var artifactsErr *ArtifactsError
+	if errors.As(err, &artifactsErr) {
+		return err
+	}
+
+	r := h.artifacts
+	r.mu.Lock()
+	root := r.dir
+	r.mu.Unlock()
+	if root == "" {
+		return err
+	}
+
+	dir, _ := h.saveArtifacts(root, err)
+	if dir == "" {
+		return err
+	}
+	return &ArtifactsError{Dir: dir, Err: err}
+}
+
+// saveArtifacts writes whatever it can collect into a new folder under root, it returns the folder
+// and the errors met
+func (h *CdpHelper) saveArtifacts(root string, cause error) (string, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(root, time.Now().Format("20060102-150405-"))
+	if err != nil {
+		return "", err
+	}
+
+	var errs []error
+	write := func(name string, data string) {
+		errs = append(errs, os.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+
+	write("error.txt", cause.Error()+"\n")
+	write("console.log", strings.Join(h.artifacts.recent(), "\n"))
+	errs = append(errs, h.ScreenShot(dir, "screenshot.png"))
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+
+	var url, html string
+	// dom.GetDocument would invalidate every NodeID the caller holds
+	err = chromedp.Run(timeoutCtx, chromedp.Location(&url),
+		chromedp.Evaluate(`document.documentElement ? document.documentElement.outerHTML : ""`, &html))
+	errs = append(errs, err)
+	if url != "" {
+		write("url.txt", url+"\n")
+	}
+	if html != "" {
+		write("page.html", html)
+	}
+
+	return dir, errors.Join(errs...)
+}
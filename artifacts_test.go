@@ -0,0 +1,77 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemoteObjectString(t *testing.T) {
+	assert.Equal(t, "hello", remoteObjectString(&runtime.RemoteObject{Type: runtime.TypeString, Value: easyjson.RawMessage(`"hello"`)}))
+	assert.Equal(t, "42", remoteObjectString(&runtime.RemoteObject{Type: runtime.TypeNumber, Value: easyjson.RawMessage(`42`)}))
+	assert.Equal(t, "NaN", remoteObjectString(&runtime.RemoteObject{Type: runtime.TypeNumber, UnserializableValue: "NaN"}))
+	assert.Equal(t, "Array(2)", remoteObjectString(&runtime.RemoteObject{Type: runtime.TypeObject, Description: "Array(2)"}))
+	assert.Equal(t, "undefined", remoteObjectString(&runtime.RemoteObject{Type: runtime.TypeUndefined}))
+}
+
+func TestArtifactRecorder_log(t *testing.T) {
+	r := &artifactRecorder{}
+	for i := 0; i < consoleBufferSize+10; i++ {
+		r.log(fmt.Sprint(i))
+	}
+	lines := r.recent()
+	assert.Len(t, lines, consoleBufferSize)
+	assert.Equal(t, "10", lines[0])
+}
+
+func TestArtifactsError(t *testing.T) {
+	err := &ArtifactsError{Dir: "artifacts/1", Err: context.DeadlineExceeded}
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, "context deadline exceeded (artifacts saved to artifacts/1)", err.Error())
+}
+
+func TestCdpHelper_ArtifactsOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><h1>checkout</h1><script>console.log("cart", 3)</script></body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	dir := t.TempDir()
+	b.ArtifactsOnError(dir)
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	err = b.WaitReadyWithTimeout(500*time.Millisecond, `#pay`)
+	var artifactsErr *ArtifactsError
+	assert.True(t, errors.As(err, &artifactsErr))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, dir, filepath.Dir(artifactsErr.Dir))
+
+	for _, name := range []string{"screenshot.png", "page.html", "url.txt", "console.log", "error.txt"} {
+		_, err = os.Stat(filepath.Join(artifactsErr.Dir, name))
+		assert.Nil(t, err, name)
+	}
+	data, err := os.ReadFile(filepath.Join(artifactsErr.Dir, "page.html"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "<h1>checkout</h1>")
+	data, err = os.ReadFile(filepath.Join(artifactsErr.Dir, "console.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "[log] cart 3")
+
+	b.ArtifactsOnError("")
+	err = b.WaitReadyWithTimeout(100*time.Millisecond, `#pay`)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, errors.As(err, &artifactsErr))
+}
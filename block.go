@@ -0,0 +1,99 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"sync"
+)
+
+// blockRules are the resource types and url patterns blocked in every tab of a session
+type blockRules struct {
+	mu    sync.Mutex
+	types []network.ResourceType
+	urls  []string
+}
+
+func (r *blockRules) add(types []network.ResourceType, urls []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types = append(r.types, types...)
+	r.urls = append(r.urls, urls...)
+}
+
+// routes returns a blocking route for every rule
+func (r *blockRules) routes() []*route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	block := func(req *InterceptedRequest) InterceptAction {
+		return Fail(network.ErrorReasonBlockedByClient)
+	}
+
+	var routes []*route
+	for _, t := range r.types {
+		routes = append(routes, &route{
+			pattern:      "*",
+			resourceType: t,
+			block:        true,
+			re:           globToRegexp("*"),
+			handler:      block,
+		})
+	}
+	for _, pattern := range r.urls {
+		routes = append(routes, &route{
+			pattern: pattern,
+			block:   true,
+			re:      globToRegexp(pattern),
+			handler: block,
+		})
+	}
+	return routes
+}
+
+// BlockResources fails the requests of the given types, e.g. network.ResourceTypeImage,
+// in every tab of the browser, including the tabs created later
+func (h *CdpHelper) BlockResources(types ...network.ResourceType) error {
+	h.session.blocking.add(types, nil)
+	return h.applyBlockingToAll()
+}
+
+// BlockURLs fails the requests whose url matches any of patterns, where '*' matches zero or more characters
+// and '?' exactly one, in every tab of the browser, including the tabs created later
+func (h *CdpHelper) BlockURLs(patterns ...string) error {
+	h.session.blocking.add(nil, patterns)
+	return h.applyBlockingToAll()
+}
+
+func (h *CdpHelper) applyBlockingToAll() error {
+	if err := h.session.root.applyBlocking(); err != nil {
+		return err
+	}
+	for _, tab := range h.session.list() {
+		if err := tab.applyBlocking(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyBlocking replaces the blocking routes of h with the rules of its session
+func (h *CdpHelper) applyBlocking() error {
+	routes := h.session.blocking.routes()
+	i := h.interceptor
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	hadBlocking := false
+	kept := i.routes[:0:0]
+	for _, r := range i.routes {
+		if r.block {
+			hadBlocking = true
+			continue
+		}
+		kept = append(kept, r)
+	}
+	if len(routes) == 0 && !hadBlocking {
+		return nil
+	}
+
+	i.routes = append(routes, kept...)
+	return h.applyRoutes()
+}
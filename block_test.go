@@ -0,0 +1,62 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestBlockRules_routes(t *testing.T) {
+	var rules blockRules
+	rules.add([]network.ResourceType{network.ResourceTypeImage}, []string{"*://tracker.com/*"})
+	i := &interceptor{routes: rules.routes()}
+	i.routes = append(i.routes, &route{pattern: "*", re: globToRegexp("*")})
+
+	r := i.match("https://a.com/logo.png", network.ResourceTypeImage)
+	assert.True(t, r.block)
+	r = i.match("https://tracker.com/t.js", network.ResourceTypeScript)
+	assert.True(t, r.block)
+	r = i.match("https://a.com/app.js", network.ResourceTypeScript)
+	assert.False(t, r.block)
+}
+
+func TestCdpHelper_BlockResources(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/track.js":
+			w.Header().Set("Content-Type", "text/javascript")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><img src="/logo.png"><script src="/track.js"></script></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	b := NewBrowserWithOptions(WithBlockedResources(network.ResourceTypeImage))
+	defer b.Close()
+	err := b.BlockURLs("*/track.js")
+	assert.Nil(t, err)
+	err = b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	tab, err := b.NewBlankTab("")
+	assert.Nil(t, err)
+	err = tab.Run(chromedp.Navigate(server.URL))
+	assert.Nil(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, requested, "/")
+	assert.NotContains(t, requested, "/logo.png")
+	assert.NotContains(t, requested, "/track.js")
+}
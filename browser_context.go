@@ -0,0 +1,46 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// NewIsolatedContext returns a new CdpHelper whose tab lives in a new incognito browser context,
+// so it and the tabs opened from it have their own cookies, storage and cache.
+// The state of WithState is loaded into it. Close the returned helper to close its tab and dispose the browser context.
+func (h *CdpHelper) NewIsolatedContext(opts ...chromedp.CreateBrowserContextOption) (*CdpHelper, error) {
+	root := h.session.root
+	// the browser must be allocated before a browser context can be created
+	if err := chromedp.Run(root.Browser.Context); err != nil {
+		return nil, h.fail(err)
+	}
+
+	isolatedContext, isolatedCancel := chromedp.NewContext(root.Browser.Context, chromedp.WithNewBrowserContext(opts...))
+	if err := chromedp.Run(isolatedContext); err != nil {
+		isolatedCancel()
+		return nil, h.fail(err)
+	}
+
+	helper, err := h.newTab(isolatedContext, isolatedCancel)
+	if err != nil {
+		return nil, err
+	}
+	if state := h.session.state; state != nil {
+		if err = helper.applyState(state); err != nil {
+			_ = helper.Close()
+			return nil, err
+		}
+	}
+	h.session.registry.put(helper.TargetID(), helper)
+
+	return helper, nil
+}
+
+// BrowserContextID returns the browser context of the current tab, it's empty for the default one
+func (h *CdpHelper) BrowserContextID() cdp.BrowserContextID {
+	c := chromedp.FromContext(h.Current.Context)
+	if c == nil {
+		return ""
+	}
+	return c.BrowserContextID
+}
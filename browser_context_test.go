@@ -0,0 +1,69 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCdpHelper_NewIsolatedContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+
+	alice, err := b.NewIsolatedContext()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, alice.BrowserContextID())
+	bob, err := b.NewIsolatedContext()
+	assert.Nil(t, err)
+	assert.NotEqual(t, alice.BrowserContextID(), bob.BrowserContextID())
+
+	err = alice.Navigate(server.URL)
+	assert.Nil(t, err)
+	err = alice.Run(chromedp.Evaluate(`document.cookie = "user=alice"`, nil))
+	assert.Nil(t, err)
+
+	var cookie string
+	err = bob.Navigate(server.URL)
+	assert.Nil(t, err)
+	err = bob.Run(chromedp.Evaluate(`document.cookie`, &cookie))
+	assert.Nil(t, err)
+	assert.Empty(t, cookie)
+
+	err = alice.Run(chromedp.Evaluate(`document.cookie`, &cookie))
+	assert.Nil(t, err)
+	assert.Equal(t, "user=alice", cookie)
+
+	assert.Nil(t, alice.Close())
+	assert.Nil(t, bob.Close())
+}
+
+func TestCdpHelper_NewIsolatedContext_state(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer server.Close()
+
+	state := &State{Cookies: []*network.Cookie{{Name: "sid", Value: "s3cret", Domain: "127.0.0.1", Path: "/", Session: true}}}
+	b := NewBrowserWithOptions(WithState(state))
+	defer b.Close()
+
+	isolated, err := b.NewIsolatedContext()
+	assert.Nil(t, err)
+	err = isolated.Navigate(server.URL)
+	assert.Nil(t, err)
+	var cookie string
+	err = isolated.Run(chromedp.Evaluate(`document.cookie`, &cookie))
+	assert.Nil(t, err)
+	assert.Equal(t, "sid=s3cret", cookie)
+	assert.Nil(t, isolated.Close())
+}
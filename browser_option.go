@@ -0,0 +1,150 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// BrowserOption configures a browser created by NewBrowserWithOptions
+type BrowserOption func(*browserConfig)
+
+type browserConfig struct {
+	allocatorOptions []chromedp.ExecAllocatorOption
+	contextOptions   []chromedp.ContextOption
+	logger           Logger
+	blockedTypes     []network.ResourceType
+	blockedURLs      []string
+	state            *State
+}
+
+func newBrowserConfig(opts ...BrowserOption) *browserConfig {
+	config := &browserConfig{
+		allocatorOptions: append(chromedp.DefaultExecAllocatorOptions[:],
+			chromedp.DisableGPU,
+			chromedp.Flag("disable-popup-blocking", true),
+			chromedp.WindowSize(1920, 1080),
+		),
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.logger != nil {
+		config.contextOptions = append(config.contextOptions, loggerContextOptions(config.logger)...)
+	}
+
+	return config
+}
+
+func loggerContextOptions(logger Logger) []chromedp.ContextOption {
+	return []chromedp.ContextOption{
+		chromedp.WithErrorf(logger.Errorf),
+		chromedp.WithDebugf(logger.Debugf),
+	}
+}
+
+// WithHeadless sets whether chrome runs in headless mode, default is true
+func WithHeadless(headless bool) BrowserOption {
+	return WithFlag("headless", headless)
+}
+
+// WithExecPath sets the chrome binary, either an absolute path or a name looked up in PATH
+func WithExecPath(path string) BrowserOption {
+	return WithAllocatorOptions(chromedp.ExecPath(path))
+}
+
+// WithUserDataDir sets the profile directory, a temporary one is used when not set
+func WithUserDataDir(dir string) BrowserOption {
+	return WithAllocatorOptions(chromedp.UserDataDir(dir))
+}
+
+// WithProxy sets the outbound proxy server, e.g. "http://127.0.0.1:8080"
+func WithProxy(proxy string) BrowserOption {
+	return WithAllocatorOptions(chromedp.ProxyServer(proxy))
+}
+
+// WithUserAgent sets the default User-Agent header
+func WithUserAgent(userAgent string) BrowserOption {
+	return WithAllocatorOptions(chromedp.UserAgent(userAgent))
+}
+
+// WithWindowSize sets the initial window size, default is 1920x1080
+func WithWindowSize(width, height int) BrowserOption {
+	return WithAllocatorOptions(chromedp.WindowSize(width, height))
+}
+
+// WithDisableGPU sets whether the GPU process is disabled, default is true
+func WithDisableGPU(disable bool) BrowserOption {
+	return WithFlag("disable-gpu", disable)
+}
+
+// WithPopupBlocking sets whether chrome blocks popups, default is false
+func WithPopupBlocking(enable bool) BrowserOption {
+	return WithFlag("disable-popup-blocking", !enable)
+}
+
+// WithNoSandbox disables the chrome sandbox, usually required when running as root
+func WithNoSandbox() BrowserOption {
+	return WithAllocatorOptions(chromedp.NoSandbox)
+}
+
+// WithIgnoreCertErrors ignores certificate errors, useful behind an intercepting proxy
+func WithIgnoreCertErrors() BrowserOption {
+	return WithAllocatorOptions(chromedp.IgnoreCertErrors)
+}
+
+// WithEnv appends environment variables in the form NAME=value to the chrome process
+func WithEnv(vars ...string) BrowserOption {
+	return WithAllocatorOptions(chromedp.Env(vars...))
+}
+
+// WithFlag passes an extra command line flag to chrome, see chromedp.Flag
+func WithFlag(name string, value any) BrowserOption {
+	return WithAllocatorOptions(chromedp.Flag(name, value))
+}
+
+// WithAllocatorOptions appends raw chromedp allocator options
+func WithAllocatorOptions(opts ...chromedp.ExecAllocatorOption) BrowserOption {
+	return func(config *browserConfig) {
+		config.allocatorOptions = append(config.allocatorOptions, opts...)
+	}
+}
+
+// WithContextOptions appends raw chromedp context options
+func WithContextOptions(opts ...chromedp.ContextOption) BrowserOption {
+	return func(config *browserConfig) {
+		config.contextOptions = append(config.contextOptions, opts...)
+	}
+}
+
+// WithLogger routes chromedp error and debug logs to logger
+func WithLogger(logger Logger) BrowserOption {
+	return func(config *browserConfig) {
+		config.logger = logger
+	}
+}
+
+// WithBlockedResources blocks the given resource types in every tab, see CdpHelper.BlockResources.
+// The browser is started right away to apply it.
+func WithBlockedResources(types ...network.ResourceType) BrowserOption {
+	return func(config *browserConfig) {
+		config.blockedTypes = append(config.blockedTypes, types...)
+	}
+}
+
+// WithBlockedURLs blocks the urls matching patterns in every tab, see CdpHelper.BlockURLs.
+// The browser is started right away to apply it.
+func WithBlockedURLs(patterns ...string) BrowserOption {
+	return func(config *browserConfig) {
+		config.blockedURLs = append(config.blockedURLs, patterns...)
+	}
+}
+
+// WithState loads state, see ReadState, into the browser. The browser is started right away to apply it,
+// and a BrowserPool loads it again each time it resets the browser.
+func WithState(state *State) BrowserOption {
+	return func(config *browserConfig) {
+		config.state = state
+	}
+}
@@ -0,0 +1,33 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewBrowserConfig(t *testing.T) {
+	config := newBrowserConfig()
+	assert.Len(t, config.allocatorOptions, len(chromedp.DefaultExecAllocatorOptions)+3)
+	assert.Empty(t, config.contextOptions)
+
+	config = newBrowserConfig(WithHeadless(false), WithProxy("http://127.0.0.1:8080"), WithLogger(&DefaultLogger{}))
+	assert.Len(t, config.allocatorOptions, len(chromedp.DefaultExecAllocatorOptions)+5)
+	assert.Len(t, config.contextOptions, 2)
+}
+
+func TestNewBrowserWithOptions(t *testing.T) {
+	b := NewBrowserWithOptions(WithHeadless(true), WithUserAgent("cdp-helper"))
+	err := b.Navigate("about:blank")
+	assert.Nil(t, err)
+	var userAgent string
+	err = b.Run(chromedp.Evaluate(`navigator.userAgent`, &userAgent))
+	assert.Nil(t, err)
+	assert.Equal(t, "cdp-helper", userAgent)
+}
+
+func TestStartBrowser(t *testing.T) {
+	b, err := StartBrowser(WithAllocatorOptions(chromedp.ExecPath("/nonexistent/chrome")), WithBlockedURLs("*.png"))
+	assert.NotNil(t, err)
+	assert.Nil(t, b)
+}
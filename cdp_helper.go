@@ -15,7 +15,9 @@ import (
 	"log"
 	"os"
 	"path"
-	"strings"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,6 +36,17 @@ type CdpHelper struct {
 	TextTimeout      time.Duration
 	DownloadTimeout  time.Duration
 	EnableScreenshot bool
+
+	session     *session
+	closer      *onceError
+	interceptor *interceptor
+	har         *harRecorder
+	artifacts   *artifactRecorder
+	nodes       *nodeRegistry
+	frame       *frameScope
+	frames      *frameSessions
+	inflight    *inflightTracker
+	crashed     *atomic.Bool
 }
 
 type Logger interface {
@@ -56,32 +69,63 @@ func (*DefaultLogger) Logf(format string, args ...any) {
 	log.Printf(format, args...)
 }
 
+// NewBrowser returns a CdpHelper with the default options, see NewBrowserWithOptions
 func NewBrowser(headless bool) *CdpHelper {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.DisableGPU,
-		chromedp.Flag("disable-popup-blocking", true),
-		chromedp.Flag("headless", headless),
-		chromedp.WindowSize(1920, 1080),
+	return NewBrowserWithOptions(WithHeadless(headless))
+}
+
+// NewBrowserWithOptions returns a CdpHelper backed by a local chrome configured by opts.
+// The errors applying WithBlockedResources, WithBlockedURLs and WithState are only logged by WithLogger,
+// use StartBrowser to get them.
+func NewBrowserWithOptions(opts ...BrowserOption) *CdpHelper {
+	config := newBrowserConfig(opts...)
+	helper, err := newBrowser(config)
+	if err != nil && config.logger != nil {
+		config.logger.Errorf("%v", err)
+	}
+	return helper
+}
+
+// StartBrowser starts a local chrome configured by opts, it returns the error starting it or applying
+// WithBlockedResources, WithBlockedURLs and WithState
+func StartBrowser(opts ...BrowserOption) (*CdpHelper, error) {
+	helper, err := newBrowser(newBrowserConfig(opts...))
+	if err == nil {
+		err = chromedp.Run(helper.Browser.Context)
+	}
+	if err != nil {
+		_ = helper.Close()
+		return nil, err
+	}
+	return helper, nil
+}
+
+// newBrowser returns a CdpHelper backed by a local chrome configured by config, and the errors applying it
+func newBrowser(config *browserConfig) (*CdpHelper, error) {
+	allocator, allocatorCancel := chromedp.NewExecAllocator(context.Background(), config.allocatorOptions...)
+	browserContext, browserCancel := chromedp.NewContext(allocator, config.contextOptions...)
+
+	helper := newRootHelper(
+		ContextWithCancel{Context: allocator, Cancel: allocatorCancel},
+		ContextWithCancel{Context: browserContext, Cancel: browserCancel},
 	)
 
-	allocator, allocatorCancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	browserContext, browserCancel := chromedp.NewContext(allocator)
-
-	helper := CdpHelper{
-		Allocator: ContextWithCancel{
-			Context: allocator,
-			Cancel:  allocatorCancel,
-		},
-		Browser: ContextWithCancel{
-			Context: browserContext,
-			Cancel:  browserCancel,
-		},
+	var errs []error
+	if len(config.blockedTypes) > 0 || len(config.blockedURLs) > 0 {
+		helper.session.blocking.add(config.blockedTypes, config.blockedURLs)
+		if err := helper.applyBlocking(); err != nil {
+			errs = append(errs, fmt.Errorf("apply blocking rules: %w", err))
+		}
 	}
 
-	helper.Current = &helper.Browser
-	helper.setDefault()
+	if config.state != nil {
+		helper.session.state = config.state
+		if err := helper.applyState(config.state); err != nil {
+			errs = append(errs, fmt.Errorf("load state: %w", err))
+		}
+	}
 
-	return &helper
+	return helper, errors.Join(errs...)
 }
 
 type RemoteBrowserOption struct {
@@ -93,29 +137,36 @@ func NewRemoteBrowser(option RemoteBrowserOption) *CdpHelper {
 	remoteAllocator, remoteAllocatorCancel := chromedp.NewRemoteAllocator(context.Background(), option.URL)
 	var opts []chromedp.ContextOption
 	if option.Logger != nil {
-		opts = append(opts,
-			chromedp.WithErrorf(option.Logger.Errorf),
-			chromedp.WithDebugf(option.Logger.Debugf),
-		)
-		opts = append(opts)
+		opts = append(opts, loggerContextOptions(option.Logger)...)
 	}
 	remoteBrowserContext, remoteBrowserCancel := chromedp.NewContext(remoteAllocator, opts...)
 
-	helper := CdpHelper{
-		Allocator: ContextWithCancel{
-			Context: remoteAllocator,
-			Cancel:  remoteAllocatorCancel,
-		},
-		Browser: ContextWithCancel{
-			Context: remoteBrowserContext,
-			Cancel:  remoteBrowserCancel,
-		},
+	return newRootHelper(
+		ContextWithCancel{Context: remoteAllocator, Cancel: remoteAllocatorCancel},
+		ContextWithCancel{Context: remoteBrowserContext, Cancel: remoteBrowserCancel},
+	)
+}
+
+// newRootHelper returns the CdpHelper of the first tab of the browser of browserContext, with its own session
+func newRootHelper(allocator ContextWithCancel, browserContext ContextWithCancel) *CdpHelper {
+	helper := &CdpHelper{
+		Allocator: allocator,
+		Browser:   browserContext,
 	}
 
 	helper.Current = &helper.Browser
+	helper.session = newSession(helper)
+	helper.closer = &onceError{}
+	helper.interceptor = &interceptor{}
+	helper.har = &harRecorder{}
+	helper.artifacts = &artifactRecorder{}
+	helper.nodes = newNodeRegistry()
+	helper.frames = newFrameSessions()
+	helper.watchCrash()
+	helper.inflight = trackInflight(helper.Current.Context)
 	helper.setDefault()
 
-	return &helper
+	return helper
 }
 
 func (h *CdpHelper) setDefault() {
@@ -137,7 +188,8 @@ func (h *CdpHelper) FullScreen() error {
 	return h.Run(chromedp.EmulateViewport(1920, 1080))
 }
 
-// NewBlankTab returns a new CdpHelper instance, and CdpHelper.Current points to the new tab
+// NewBlankTab returns a new CdpHelper instance, and CdpHelper.Current points to the new tab.
+// Close the returned helper to close the tab only.
 func (h *CdpHelper) NewBlankTab(targetId string) (*CdpHelper, error) {
 	if targetId == "" {
 		targetId = "_blank"
@@ -158,45 +210,50 @@ func (h *CdpHelper) NewBlankTab(targetId string) (*CdpHelper, error) {
 
 	err := chromedp.Run(h.Current.Context, chromedp.Evaluate(js, &res))
 	if err != nil {
-		return nil, err
+		return nil, h.fail(err)
 	}
 
 	id := <-ch
 	targetContext, targetCancel := chromedp.NewContext(h.Current.Context, chromedp.WithTargetID(id))
 
-	helper := CdpHelper{
-		Allocator: h.Allocator,
-		Browser:   h.Browser,
-		Current: &ContextWithCancel{
-			Context: targetContext,
-			Cancel:  targetCancel,
-		},
+	helper, err := h.newTab(targetContext, targetCancel)
+	if err != nil {
+		return nil, err
 	}
+	h.session.registry.put(id, helper)
 
-	return &helper, nil
+	return helper, nil
 }
 
+// Navigate loads url in the current tab, a page that fails to load returns a *NavigationError
 func (h *CdpHelper) Navigate(url string) error {
-	return h.Run(chromedp.Navigate(url))
+	return h.fail(navigationError(url, chromedp.Run(h.Current.Context, chromedp.Navigate(url))))
 }
 
 func (h *CdpHelper) NavigateWithTimeout(url string, timeout time.Duration) error {
-	return h.RunWithTimeout(timeout, chromedp.Navigate(url))
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, timeout)
+	defer timeoutCancel()
+	return h.fail(navigationError(url, chromedp.Run(timeoutCtx, chromedp.Navigate(url))))
 }
 
 func (h *CdpHelper) NodeTextContent(sel any, opts ...chromedp.QueryOption) (string, error) {
 	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.TextTimeout)
 	defer timeoutCancel()
 	var text string
-	err := chromedp.Run(timeoutCtx, chromedp.TextContent(sel, &text, opts...))
+	err := chromedp.Run(timeoutCtx, chromedp.TextContent(sel, &text, h.queryOpts(sel, opts)...))
 	if err != nil {
-		return "", err
+		return "", h.fail(err)
 	}
 
 	return text, nil
 }
 
+// Nodes returns the nodes matching sel with their subtrees. The helper remembers how they were located, so the
+// child node helpers can find them again after their NodeID became stale, or fail with ErrStaleNode.
+// A string selector like "my-app >>> settings-panel >>> input" pierces open shadow roots, every part is matched
+// inside the shadow roots of the previous matches, the selector based methods and the child node helpers accept it too.
 func (h *CdpHelper) Nodes(sel any, opts ...chromedp.QueryOption) ([]*cdp.Node, error) {
+	opts = h.queryOpts(sel, opts)
 	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
 	defer timeoutCancel()
 
@@ -204,14 +261,15 @@ func (h *CdpHelper) Nodes(sel any, opts ...chromedp.QueryOption) ([]*cdp.Node, e
 	err := chromedp.Run(timeoutCtx, chromedp.Nodes(sel, &nodes, opts...))
 
 	if err != nil {
-		return nil, err
+		return nil, h.fail(err)
 	}
 
-	for _, node := range nodes {
+	for i, node := range nodes {
 		err = chromedp.Run(timeoutCtx, dom.RequestChildNodes(node.NodeID).WithDepth(-1).WithPierce(true))
 		if err != nil {
-			return nil, err
+			return nil, h.fail(err)
 		}
+		h.nodes.remember(node, &nodeLocator{sel: sel, opts: opts, index: i})
 	}
 
 	return nodes, err
@@ -219,9 +277,14 @@ func (h *CdpHelper) Nodes(sel any, opts ...chromedp.QueryOption) ([]*cdp.Node, e
 
 func (h *CdpHelper) ChildNodes(parent *cdp.Node, cssSel string) ([]cdp.NodeID, error) {
 	executor := h.NewTargetExecutor(h.Current.Context)
-	nodeIDs, err := dom.QuerySelectorAll(parent.NodeID, cssSel).Do(executor)
+	var nodeIDs []cdp.NodeID
+	err := h.withNode(executor, refOf(parent), func(parentID cdp.NodeID) error {
+		var err error
+		nodeIDs, err = querySelectorAll(executor, parentID, cssSel)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, h.fail(err)
 	}
 	return nodeIDs, nil
 }
@@ -230,28 +293,43 @@ func (h *CdpHelper) ChildNodeTextContent(parent *cdp.Node, cssSel string) (strin
 	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.TextTimeout)
 	defer timeoutCancel()
 
-	executor, childNodeID, err := h.ChildNode(timeoutCtx, parent.NodeID, cssSel)
-	if err != nil {
-		return "", err
-	}
-
 	var text string
-	err = chromedp.TextContent([]cdp.NodeID{childNodeID}, &text, chromedp.ByNodeID).Do(executor)
+	err := h.withNode(h.NewTargetExecutor(timeoutCtx), refOf(parent), func(parentID cdp.NodeID) error {
+		executor, childNodeID, err := h.ChildNode(timeoutCtx, parentID, cssSel)
+		if err != nil {
+			return err
+		}
+		return chromedp.TextContent([]cdp.NodeID{childNodeID}, &text, chromedp.ByNodeID).Do(executor)
+	})
 	if err != nil {
-		return "", err
+		return "", h.fail(err)
 	}
 
 	return text, nil
 }
 
+// Download sends the GUID of every completed download saved into path, in the order they completed.
+// The channel is closed after the DownloadTimeout or the returned cancel.
+//
+// Deprecated: use NewDownloadManager, which reports file paths, progress and failures.
 func (h *CdpHelper) Download(path string, isNewTarget bool) (*chan string, context.Context, func(), error) {
-	done := make(chan string, 1)
+	done := make(chan string)
+	var mu sync.Mutex
+	var guids []string
+	queued := make(chan struct{}, 1)
 
 	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.DownloadTimeout)
 	listenEvent := func(ev any) {
 		if v, ok := ev.(*browser.EventDownloadProgress); ok {
 			if v.State == browser.DownloadProgressStateCompleted {
-				done <- v.GUID
+				// the listener must not block, the GUIDs are sent in background
+				mu.Lock()
+				guids = append(guids, v.GUID)
+				mu.Unlock()
+				select {
+				case queued <- struct{}{}:
+				default:
+				}
 			}
 		}
 	}
@@ -275,45 +353,64 @@ func (h *CdpHelper) Download(path string, isNewTarget bool) (*chan string, conte
 		Do(executor)
 	if err != nil {
 		timeoutCancel()
-		return nil, nil, nil, err
+		return nil, nil, nil, h.fail(err)
 	}
 
-	cancel := func() {
-		close(done)
-		timeoutCancel()
-	}
+	go func() {
+		defer close(done)
+		for {
+			mu.Lock()
+			pending := guids
+			guids = nil
+			mu.Unlock()
+			for _, guid := range pending {
+				select {
+				case done <- guid:
+				case <-timeoutCtx.Done():
+					return
+				}
+			}
 
-	return &done, timeoutCtx, cancel, nil
+			select {
+			case <-queued:
+			case <-timeoutCtx.Done():
+				return
+			}
+		}
+	}()
+
+	return &done, timeoutCtx, timeoutCancel, nil
 }
 
 func (h *CdpHelper) Click(sel any, opts ...chromedp.QueryOption) error {
-	return h.Run(chromedp.Click(sel, opts...))
+	return h.Run(chromedp.Click(sel, h.queryOpts(sel, opts)...))
 }
 
 func (h *CdpHelper) ClickChild(parent *cdp.Node, cssSel string, opts ...chromedp.MouseOption) error {
 	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
 	defer timeoutCancel()
-	executor, childNodeID, err := h.ChildNode(timeoutCtx, parent.NodeID, cssSel)
-	if err != nil {
-		return err
-	}
-
 	var childNode *cdp.Node
-	childNode, err = dom.DescribeNode().WithNodeID(childNodeID).Do(executor)
+	err := h.withNode(h.NewTargetExecutor(timeoutCtx), refOf(parent), func(parentID cdp.NodeID) error {
+		executor, childNodeID, err := h.ChildNode(timeoutCtx, parentID, cssSel)
+		if err != nil {
+			return err
+		}
+		childNode, err = describe(executor, childNodeID)
+		return err
+	})
 	if err != nil {
-		return nil
+		return h.fail(err)
 	}
-	childNode.NodeID = childNodeID
 
 	return h.Run(chromedp.MouseClickNode(childNode, opts...))
 }
 
 func (h *CdpHelper) SendKeys(sel any, v string, opts ...chromedp.QueryOption) error {
-	return h.Run(chromedp.SendKeys(sel, v, opts...))
+	return h.Run(chromedp.SendKeys(sel, v, h.queryOpts(sel, opts)...))
 }
 
 func (h *CdpHelper) WaitReady(sel any, opts ...chromedp.QueryOption) error {
-	return h.Run(chromedp.WaitReady(sel, opts...))
+	return h.Run(chromedp.WaitReady(sel, h.queryOpts(sel, opts)...))
 }
 
 func (h *CdpHelper) Sleep(d time.Duration) error {
@@ -322,7 +419,7 @@ func (h *CdpHelper) Sleep(d time.Duration) error {
 
 func (h *CdpHelper) Attributes(sel any, opts ...chromedp.QueryOption) (map[string]string, error) {
 	var attributes map[string]string
-	err := h.Run(chromedp.Attributes(sel, &attributes, opts...))
+	err := h.Run(chromedp.Attributes(sel, &attributes, h.queryOpts(sel, opts)...))
 	if err != nil {
 		return nil, err
 	}
@@ -332,7 +429,7 @@ func (h *CdpHelper) Attributes(sel any, opts ...chromedp.QueryOption) (map[strin
 
 func (h *CdpHelper) AttributesAll(sel any, opts ...chromedp.QueryOption) ([]map[string]string, error) {
 	var attributes []map[string]string
-	err := h.Run(chromedp.AttributesAll(sel, &attributes, opts...))
+	err := h.Run(chromedp.AttributesAll(sel, &attributes, h.queryOpts(sel, opts)...))
 	if err != nil {
 		return nil, err
 	}
@@ -341,21 +438,21 @@ func (h *CdpHelper) AttributesAll(sel any, opts ...chromedp.QueryOption) ([]map[
 }
 
 func (h *CdpHelper) SetAttributeValue(sel any, name string, value string, opts ...chromedp.QueryOption) error {
-	return h.Run(chromedp.SetAttributeValue(sel, name, value, opts...))
+	return h.Run(chromedp.SetAttributeValue(sel, name, value, h.queryOpts(sel, opts)...))
 }
 
 func (h *CdpHelper) SetAttributes(sel any, attributes map[string]string, opts ...chromedp.QueryOption) error {
-	return h.Run(chromedp.SetAttributes(sel, attributes, opts...))
+	return h.Run(chromedp.SetAttributes(sel, attributes, h.queryOpts(sel, opts)...))
 }
 
 func (h *CdpHelper) Run(actions ...chromedp.Action) error {
-	return chromedp.Run(h.Current.Context, actions...)
+	return h.fail(chromedp.Run(h.Current.Context, actions...))
 }
 
 func (h *CdpHelper) RunWithTimeout(t time.Duration, actions ...chromedp.Action) error {
 	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, t)
 	defer timeoutCancel()
-	return chromedp.Run(timeoutCtx, actions...)
+	return h.fail(chromedp.Run(timeoutCtx, actions...))
 }
 
 func (h *CdpHelper) Tasks(actions ...chromedp.Action) error {
@@ -367,12 +464,12 @@ func (h *CdpHelper) ChildNode(ctx context.Context, parent cdp.NodeID, cssSel str
 	var nodeID cdp.NodeID
 	var err error
 	if cssSel != "" {
-		nodeID, err = dom.QuerySelector(parent, cssSel).Do(executor)
+		nodeID, err = querySelector(executor, parent, cssSel)
 		if err != nil {
 			return nil, 0, err
 		}
 		if nodeID == cdp.EmptyNodeID {
-			return nil, 0, errors.New("empty nodeID")
+			return nil, 0, fmt.Errorf("child node %q: %w", cssSel, ErrNotFound)
 		}
 	} else {
 		nodeID = parent
@@ -384,7 +481,12 @@ func (h *CdpHelper) ChildNode(ctx context.Context, parent cdp.NodeID, cssSel str
 func (h *CdpHelper) HasChildNode(parent *cdp.Node, cssSel string) (cdp.NodeID, bool) {
 	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
 	defer timeoutCancel()
-	_, nodeID, err := h.ChildNode(timeoutCtx, parent.NodeID, cssSel)
+	var nodeID cdp.NodeID
+	err := h.withNode(h.NewTargetExecutor(timeoutCtx), refOf(parent), func(parentID cdp.NodeID) error {
+		var err error
+		_, nodeID, err = h.ChildNode(timeoutCtx, parentID, cssSel)
+		return err
+	})
 	if err != nil {
 		return 0, false
 	}
@@ -397,9 +499,9 @@ func (h *CdpHelper) ComputedStyle(sel any, opts ...chromedp.QueryOption) (map[st
 	executor := h.NewTargetExecutor(timeoutCtx)
 
 	var styles []*css.ComputedStyleProperty
-	err := chromedp.ComputedStyle(sel, &styles, opts...).Do(executor)
+	err := chromedp.ComputedStyle(sel, &styles, h.queryOpts(sel, opts)...).Do(executor)
 	if err != nil {
-		return nil, err
+		return nil, h.fail(err)
 	}
 
 	style := make(map[string]string)
@@ -410,28 +512,18 @@ func (h *CdpHelper) ComputedStyle(sel any, opts ...chromedp.QueryOption) (map[st
 	return style, nil
 }
 
+// ScreenShot saves the viewport of the current tab as a PNG file, see Screenshot for more options
 func (h *CdpHelper) ScreenShot(dir string, filename string) error {
-	return h.Run(chromedp.ActionFunc(func(ctx context.Context) error {
-		if !h.EnableScreenshot {
-			return nil
-		}
-		var data []byte
-		err := chromedp.CaptureScreenshot(&data).Do(ctx)
-		if err != nil {
-			return err
-		}
-
-		err = os.WriteFile(path.Join(dir, filename), data, 0644)
-		if err != nil {
-			return err
-		}
+	data, err := h.Screenshot()
+	if err != nil || data == nil {
+		return err
+	}
 
-		return nil
-	}))
+	return os.WriteFile(path.Join(dir, filename), data, 0644)
 }
 
 func (h *CdpHelper) Upload(sel any, files []string, opts ...chromedp.QueryOption) error {
-	return h.Run(chromedp.SetUploadFiles(sel, files, opts...))
+	return h.Run(chromedp.SetUploadFiles(sel, files, h.queryOpts(sel, opts)...))
 }
 
 func (h *CdpHelper) NewBrowserExecutor(ctx context.Context) context.Context {
@@ -447,37 +539,27 @@ func (h *CdpHelper) NewTargetExecutor(ctx context.Context) context.Context {
 }
 
 func (h *CdpHelper) WaitReadyWithTimeout(timeout time.Duration, sel any, opts ...chromedp.QueryOption) error {
-	return h.RunWithTimeout(timeout, chromedp.WaitReady(sel, opts...))
+	return h.RunWithTimeout(timeout, chromedp.WaitReady(sel, h.queryOpts(sel, opts)...))
 }
 
+// ListenRequest sends the body of the first XHR or fetch response whose url contains uri, or nil on failure.
+// See WatchResponses to watch more responses.
 func (h *CdpHelper) ListenRequest(uri string) chan []byte {
-	ch := make(chan []byte)
-	var requestID network.RequestID
-	chromedp.ListenTarget(h.Current.Context, func(ev interface{}) {
-		switch ev := ev.(type) {
-		case *network.EventRequestWillBeSent:
-			if strings.Contains(ev.Request.URL, uri) {
-				requestID = ev.RequestID
-			}
-		case *network.EventResponseReceived:
-			if ev.Type == "XHR" && ev.RequestID == requestID {
-				go func(ctx context.Context) {
-					_ = chromedp.ActionFunc(func(ctx context.Context) error {
-						c := chromedp.FromContext(ctx)
-						executor := cdp.WithExecutor(ctx, c.Target)
-						data, err := network.GetResponseBody(requestID).Do(executor)
-						if err != nil {
-							ch <- nil
-						} else {
-							ch <- data
-						}
-						close(ch)
-						return nil
-					}).Do(ctx)
-				}(h.Current.Context)
-
-			}
-		}
+	ch := make(chan []byte, 1)
+	ctx, cancel := context.WithCancel(h.Current.Context)
+	responses := h.WatchResponses(ctx, ResponseWatcher{
+		URL:   regexp.MustCompile(regexp.QuoteMeta(uri)),
+		Types: []network.ResourceType{network.ResourceTypeXHR, network.ResourceTypeFetch},
 	})
+	go func() {
+		defer close(ch)
+		defer cancel()
+		resp, ok := <-responses
+		if ok && resp.Err == nil {
+			ch <- resp.Body
+		} else {
+			ch <- nil
+		}
+	}()
 	return ch
 }
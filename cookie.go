@@ -0,0 +1,195 @@
+package cdp_helper
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cookie is a browser cookie, a zero Expires means a session cookie
+type Cookie struct {
+	Name     string
+	Value    string
+	Domain   string
+	Path     string
+	Expires  time.Time
+	HttpOnly bool
+	Secure   bool
+	SameSite http.SameSite
+	// URL is only used by SetCookie, it sets the default domain, path and scheme of the cookie
+	URL string
+}
+
+// CookieFromHTTP converts c, url is used by SetCookie when c has no domain
+func CookieFromHTTP(c *http.Cookie, url string) Cookie {
+	cookie := Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires,
+		HttpOnly: c.HttpOnly,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+		URL:      url,
+	}
+	if c.MaxAge > 0 {
+		cookie.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+	}
+	return cookie
+}
+
+// HTTPCookie converts c to a net/http cookie, e.g. to reuse the session in an http.Client
+func (c Cookie) HTTPCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires,
+		HttpOnly: c.HttpOnly,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+	}
+}
+
+func cookieFromNetwork(c *network.Cookie) Cookie {
+	cookie := Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		HttpOnly: c.HTTPOnly,
+		Secure:   c.Secure,
+	}
+	if !c.Session {
+		cookie.Expires = time.Unix(0, int64(c.Expires*float64(time.Second)))
+	}
+	switch c.SameSite {
+	case network.CookieSameSiteStrict:
+		cookie.SameSite = http.SameSiteStrictMode
+	case network.CookieSameSiteLax:
+		cookie.SameSite = http.SameSiteLaxMode
+	case network.CookieSameSiteNone:
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	return cookie
+}
+
+func (c Cookie) param() *network.CookieParam {
+	param := &network.CookieParam{
+		Name:     c.Name,
+		Value:    c.Value,
+		URL:      c.URL,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HTTPOnly: c.HttpOnly,
+	}
+	if !c.Expires.IsZero() {
+		expires := cdp.TimeSinceEpoch(c.Expires)
+		param.Expires = &expires
+	}
+	switch c.SameSite {
+	case http.SameSiteStrictMode:
+		param.SameSite = network.CookieSameSiteStrict
+	case http.SameSiteLaxMode:
+		param.SameSite = network.CookieSameSiteLax
+	case http.SameSiteNoneMode:
+		param.SameSite = network.CookieSameSiteNone
+	}
+	return param
+}
+
+// CookieFilter selects cookies for DeleteCookies, empty fields match any cookie
+type CookieFilter struct {
+	Name string
+	// Domain matches the cookie domain and its subdomains, a leading dot is ignored
+	Domain string
+	Path   string
+}
+
+func (f CookieFilter) match(c *network.Cookie) bool {
+	if f.Name != "" && f.Name != c.Name {
+		return false
+	}
+	if f.Path != "" && f.Path != c.Path {
+		return false
+	}
+	if f.Domain != "" {
+		domain := strings.TrimPrefix(f.Domain, ".")
+		cookieDomain := strings.TrimPrefix(c.Domain, ".")
+		if cookieDomain != domain && !strings.HasSuffix(cookieDomain, "."+domain) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cookies returns the cookies sent to urls, default is the url of the current page
+func (h *CdpHelper) Cookies(urls ...string) ([]Cookie, error) {
+	var cookies []*network.Cookie
+	err := h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		params := network.GetCookies()
+		if len(urls) > 0 {
+			params = params.WithUrls(urls)
+		}
+		cookies, err = params.Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		result = append(result, cookieFromNetwork(c))
+	}
+	return result, nil
+}
+
+// SetCookie sets c in the browser context of the current tab, c needs a Domain or a URL
+func (h *CdpHelper) SetCookie(c Cookie) error {
+	return h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		return storage.SetCookies([]*network.CookieParam{c.param()}).
+			WithBrowserContextID(h.BrowserContextID()).
+			Do(h.NewBrowserExecutor(ctx))
+	}))
+}
+
+// DeleteCookies deletes the cookies of the browser context of the current tab matching filter
+func (h *CdpHelper) DeleteCookies(filter CookieFilter) error {
+	return h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		executor := h.NewBrowserExecutor(ctx)
+		cookies, err := storage.GetCookies().WithBrowserContextID(h.BrowserContextID()).Do(executor)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range cookies {
+			if !filter.match(c) {
+				continue
+			}
+			err = network.DeleteCookies(c.Name).WithDomain(c.Domain).WithPath(c.Path).Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// ClearCookies deletes all cookies of the browser context of the current tab
+func (h *CdpHelper) ClearCookies() error {
+	return h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		return storage.ClearCookies().
+			WithBrowserContextID(h.BrowserContextID()).
+			Do(h.NewBrowserExecutor(ctx))
+	}))
+}
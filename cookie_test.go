@@ -0,0 +1,77 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCookie_convert(t *testing.T) {
+	expires := time.Unix(1900000000, 0)
+	c := CookieFromHTTP(&http.Cookie{Name: "sid", Value: "1", Path: "/", Expires: expires, HttpOnly: true, SameSite: http.SameSiteLaxMode}, "https://example.com")
+	assert.Equal(t, "https://example.com", c.URL)
+
+	param := c.param()
+	assert.Equal(t, network.CookieSameSiteLax, param.SameSite)
+	assert.Equal(t, expires, param.Expires.Time())
+	assert.True(t, param.HTTPOnly)
+
+	back := cookieFromNetwork(&network.Cookie{Name: "sid", Value: "1", Domain: "example.com", Path: "/", Expires: 1900000000, HTTPOnly: true, SameSite: network.CookieSameSiteLax})
+	assert.Equal(t, expires, back.Expires)
+	assert.Equal(t, &http.Cookie{Name: "sid", Value: "1", Domain: "example.com", Path: "/", Expires: expires, HttpOnly: true, SameSite: http.SameSiteLaxMode}, back.HTTPCookie())
+
+	session := cookieFromNetwork(&network.Cookie{Name: "tmp", Expires: -1, Session: true})
+	assert.True(t, session.Expires.IsZero())
+	assert.Nil(t, session.param().Expires)
+}
+
+func TestCookieFilter_match(t *testing.T) {
+	c := &network.Cookie{Name: "sid", Domain: ".shop.example.com", Path: "/"}
+	assert.True(t, CookieFilter{}.match(c))
+	assert.True(t, CookieFilter{Domain: "example.com"}.match(c))
+	assert.True(t, CookieFilter{Name: "sid", Domain: ".shop.example.com", Path: "/"}.match(c))
+	assert.False(t, CookieFilter{Domain: "ample.com"}.match(c))
+	assert.False(t, CookieFilter{Name: "token"}.match(c))
+	assert.False(t, CookieFilter{Path: "/admin"}.match(c))
+}
+
+func TestCdpHelper_Cookies(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("token"); err == nil {
+			received = c.Value
+		}
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s1", Path: "/"})
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.SetCookie(Cookie{Name: "token", Value: "t1", URL: server.URL})
+	assert.Nil(t, err)
+	err = b.Navigate(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", received)
+
+	cookies, err := b.Cookies()
+	assert.Nil(t, err)
+	assert.Len(t, cookies, 2)
+
+	err = b.DeleteCookies(CookieFilter{Name: "token"})
+	assert.Nil(t, err)
+	cookies, err = b.Cookies(server.URL)
+	assert.Nil(t, err)
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "sid", cookies[0].Name)
+
+	err = b.ClearCookies()
+	assert.Nil(t, err)
+	cookies, err = b.Cookies(server.URL)
+	assert.Nil(t, err)
+	assert.Empty(t, cookies)
+}
@@ -0,0 +1,390 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DownloadState is the state of a Download
+type DownloadState string
+
+const (
+	DownloadInProgress  DownloadState = "inProgress"
+	DownloadCompleted   DownloadState = "completed"
+	DownloadCanceled    DownloadState = "canceled"
+	DownloadInterrupted DownloadState = "interrupted" // the manager was closed before chrome finished
+)
+
+// Download is a file downloaded by chrome and tracked by a DownloadManager
+type Download struct {
+	GUID              string
+	URL               string
+	SuggestedFilename string
+
+	manager  *DownloadManager
+	mu       sync.Mutex
+	path     string
+	received int64
+	total    int64
+	state    DownloadState
+	err      error
+	done     chan struct{}
+}
+
+// Path returns the final path of the file, it's empty until the download is completed
+func (d *Download) Path() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.path
+}
+
+// Progress returns the received and total bytes, total is 0 when unknown
+func (d *Download) Progress() (received int64, total int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.received, d.total
+}
+
+// State returns the current state of the download
+func (d *Download) State() DownloadState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state
+}
+
+// Wait blocks until the download reaches a terminal state or ctx is done,
+// it returns nil only when the download is completed
+func (d *Download) Wait(ctx context.Context) error {
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	if d.state != DownloadCompleted {
+		return fmt.Errorf("download %s %s", d.SuggestedFilename, d.state)
+	}
+	return nil
+}
+
+// Cancel asks chrome to cancel the download
+func (d *Download) Cancel() error {
+	h := d.manager.helper
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+	return browser.CancelDownload(d.GUID).
+		WithBrowserContextID(h.BrowserContextID()).
+		Do(h.NewBrowserExecutor(timeoutCtx))
+}
+
+// finish sets the terminal state, it must be called with d.mu held
+func (d *Download) finish(state DownloadState, err error) {
+	if d.state != DownloadInProgress {
+		return
+	}
+	d.state = state
+	d.err = err
+	close(d.done)
+}
+
+// DownloadManager tracks the downloads of the browser context of a tab
+type DownloadManager struct {
+	helper    *CdpHelper
+	contextID cdp.BrowserContextID
+	dir       string
+	mu        sync.Mutex
+	downloads map[string]*Download
+	order     []*Download
+	pending   []*Download   // the downloads begun and not got by Next yet
+	began     chan struct{} // signaled when pending grows
+	renaming  sync.WaitGroup
+	cancel    context.CancelFunc
+	closed    bool
+}
+
+// NewDownloadManager saves the downloads of the browser context of the current tab into dir,
+// including downloads started from other tabs of the same context. While it's open the downloads
+// are saved by it instead of the managers opened before, closing it gives them back to the last one.
+func (h *CdpHelper) NewDownloadManager(dir string) (*DownloadManager, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	m := &DownloadManager{
+		helper:    h,
+		contextID: h.BrowserContextID(),
+		dir:       dir,
+		downloads: make(map[string]*Download),
+		began:     make(chan struct{}, 1),
+	}
+
+	var listenCtx context.Context
+	listenCtx, m.cancel = context.WithCancel(h.Current.Context)
+	chromedp.ListenBrowser(listenCtx, m.handle)
+
+	err = h.Run(chromedp.ActionFunc(m.allow))
+	if err != nil {
+		m.cancel()
+		return nil, err
+	}
+	h.session.openDownloads(m)
+
+	return m, nil
+}
+
+// allow makes chrome save the downloads of the browser context into m.dir
+func (m *DownloadManager) allow(ctx context.Context) error {
+	return browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
+		WithBrowserContextID(m.contextID).
+		WithDownloadPath(m.dir).
+		WithEventsEnabled(true).
+		Do(m.helper.NewBrowserExecutor(ctx))
+}
+
+func (s *session) openDownloads(m *DownloadManager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.downloads[m.contextID] = append(s.downloads[m.contextID], m)
+}
+
+// closeDownloads forgets m and returns the manager which saves the downloads of its browser context now
+func (s *session) closeDownloads(m *DownloadManager) *DownloadManager {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	managers := s.downloads[m.contextID]
+	for i, manager := range managers {
+		if manager == m {
+			managers = append(managers[:i:i], managers[i+1:]...)
+			break
+		}
+	}
+	if len(managers) == 0 {
+		delete(s.downloads, m.contextID)
+		return nil
+	}
+	s.downloads[m.contextID] = managers
+	return managers[len(managers)-1]
+}
+
+// activeDownloads tells whether m saves the downloads of its browser context
+func (s *session) activeDownloads(m *DownloadManager) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	managers := s.downloads[m.contextID]
+	return len(managers) > 0 && managers[len(managers)-1] == m
+}
+
+func (m *DownloadManager) handle(ev any) {
+	switch ev := ev.(type) {
+	case *browser.EventDownloadWillBegin:
+		d := &Download{
+			GUID:              ev.GUID,
+			URL:               ev.URL,
+			SuggestedFilename: ev.SuggestedFilename,
+			manager:           m,
+			state:             DownloadInProgress,
+			done:              make(chan struct{}),
+		}
+		// chrome saves it into the dir of another manager
+		if !m.helper.session.activeDownloads(m) {
+			return
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.closed {
+			return
+		}
+		m.downloads[ev.GUID] = d
+		m.order = append(m.order, d)
+		m.pending = append(m.pending, d)
+		m.signal()
+	case *browser.EventDownloadProgress:
+		m.mu.Lock()
+		d, ok := m.downloads[ev.GUID]
+		closed := m.closed
+		completed := ok && !closed && ev.State == browser.DownloadProgressStateCompleted
+		if completed {
+			m.renaming.Add(1)
+		}
+		m.mu.Unlock()
+		if !ok || closed {
+			return
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.received = int64(ev.ReceivedBytes)
+		d.total = int64(ev.TotalBytes)
+		switch ev.State {
+		case browser.DownloadProgressStateCompleted:
+			// the listener must not block, move the file in background
+			go func() {
+				defer m.renaming.Done()
+				path, err := m.rename(d)
+				d.mu.Lock()
+				defer d.mu.Unlock()
+				d.path = path
+				d.finish(DownloadCompleted, err)
+			}()
+		case browser.DownloadProgressStateCanceled:
+			d.finish(DownloadCanceled, nil)
+		}
+	}
+}
+
+// rename moves the file chrome saved as its GUID to its suggested filename, without overwriting other files
+func (m *DownloadManager) rename(d *Download) (string, error) {
+	src := filepath.Join(m.dir, d.GUID)
+	name := filepath.Base(d.SuggestedFilename)
+	if name == "." || name == string(filepath.Separator) || name == "" {
+		return src, nil
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	dst := filepath.Join(m.dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dst = filepath.Join(m.dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		return src, err
+	}
+	return dst, nil
+}
+
+// signal wakes up a Next waiting for a download, m.mu must be held
+func (m *DownloadManager) signal() {
+	select {
+	case m.began <- struct{}{}:
+	default:
+	}
+}
+
+// Next waits for the next download to begin
+func (m *DownloadManager) Next(ctx context.Context) (*Download, error) {
+	for {
+		m.mu.Lock()
+		if len(m.pending) > 0 {
+			d := m.pending[0]
+			m.pending = m.pending[1:]
+			if len(m.pending) > 0 {
+				// another Next may be waiting
+				m.signal()
+			}
+			m.mu.Unlock()
+			return d, nil
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-m.began:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Downloads returns all downloads in the order they began
+func (m *DownloadManager) Downloads() []*Download {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	downloads := make([]*Download, len(m.order))
+	copy(downloads, m.order)
+	return downloads
+}
+
+// Close stops tracking downloads and gives the downloads back to the manager opened before it,
+// or restores the default download behavior if there is none. The downloads chrome completed
+// are moved to their final path first, the downloads still in progress become DownloadInterrupted.
+func (m *DownloadManager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.cancel()
+	downloads := m.order
+	m.mu.Unlock()
+
+	m.renaming.Wait()
+	for _, d := range downloads {
+		d.mu.Lock()
+		d.finish(DownloadInterrupted, nil)
+		d.mu.Unlock()
+	}
+
+	h := m.helper
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+	if prev := h.session.closeDownloads(m); prev != nil {
+		return prev.allow(timeoutCtx)
+	}
+	return browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorDefault).
+		WithBrowserContextID(m.contextID).
+		Do(h.NewBrowserExecutor(timeoutCtx))
+}
+
+// DownloadTo runs trigger, which must start exactly one download, and streams the downloaded file to w
+// within DownloadTimeout. It returns the suggested filename, the file is saved into a temporary dir
+// which is removed before returning.
+func (h *CdpHelper) DownloadTo(w io.Writer, trigger func() error) (string, error) {
+	dir, err := os.MkdirTemp("", "cdp-helper-download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := h.NewDownloadManager(dir)
+	if err != nil {
+		return "", err
+	}
+	defer m.Close()
+
+	if err = trigger(); err != nil {
+		return "", err
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.DownloadTimeout)
+	defer timeoutCancel()
+	d, err := m.Next(timeoutCtx)
+	if err != nil {
+		return "", h.classify(err)
+	}
+	if err = d.Wait(timeoutCtx); err != nil {
+		_ = d.Cancel()
+		return d.SuggestedFilename, h.classify(err)
+	}
+
+	f, err := os.Open(d.Path())
+	if err != nil {
+		return d.SuggestedFilename, err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return d.SuggestedFilename, err
+}
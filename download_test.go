@@ -0,0 +1,156 @@
+package cdp_helper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadManager_rename(t *testing.T) {
+	dir := t.TempDir()
+	m := &DownloadManager{dir: dir}
+	for i, guid := range []string{"guid-1", "guid-2"} {
+		err := os.WriteFile(filepath.Join(dir, guid), []byte{byte(i)}, 0644)
+		assert.Nil(t, err)
+	}
+
+	path, err := m.rename(&Download{GUID: "guid-1", SuggestedFilename: "report.csv"})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "report.csv"), path)
+	path, err = m.rename(&Download{GUID: "guid-2", SuggestedFilename: "../report.csv"})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "report (1).csv"), path)
+}
+
+func TestDownloadManager_handle(t *testing.T) {
+	dir := t.TempDir()
+	h := &CdpHelper{session: newSession(nil)}
+	m := &DownloadManager{helper: h, dir: dir, downloads: make(map[string]*Download), began: make(chan struct{}, 1)}
+	h.session.openDownloads(m)
+	for i := 0; i < 100; i++ {
+		m.handle(&browser.EventDownloadWillBegin{GUID: fmt.Sprint("guid-", i), SuggestedFilename: "report.csv"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 100; i++ {
+		d, err := m.Next(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprint("guid-", i), d.GUID)
+	}
+	_, err := m.Next(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = os.WriteFile(filepath.Join(dir, "guid-0"), []byte("a,b"), 0644)
+	assert.Nil(t, err)
+	m.handle(&browser.EventDownloadProgress{GUID: "guid-0", State: browser.DownloadProgressStateCompleted})
+	m.renaming.Wait()
+	d := m.Downloads()[0]
+	assert.Equal(t, DownloadCompleted, d.State())
+	assert.Equal(t, filepath.Join(dir, "report.csv"), d.Path())
+
+	// a manager opened later saves the next downloads
+	later := &DownloadManager{helper: h, dir: t.TempDir()}
+	h.session.openDownloads(later)
+	m.handle(&browser.EventDownloadWillBegin{GUID: "guid-100"})
+	assert.Len(t, m.Downloads(), 100)
+	assert.Same(t, m, h.session.closeDownloads(later))
+	assert.Nil(t, h.session.closeDownloads(m))
+}
+
+func TestCdpHelper_NewDownloadManager(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/report.csv", "/data.csv":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", "attachment; filename="+r.URL.Path[1:])
+			_, _ = w.Write([]byte("a,b\n1,2\n"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a id="report" href="/report.csv">report</a><a id="data" href="/data.csv">data</a></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	m, err := b.NewDownloadManager(dir)
+	assert.Nil(t, err)
+	defer m.Close()
+
+	err = b.Click(`#report`)
+	assert.Nil(t, err)
+	err = b.Click(`#data`)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		d, err := m.Next(ctx)
+		assert.Nil(t, err)
+		err = d.Wait(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, DownloadCompleted, d.State())
+		assert.Equal(t, filepath.Join(dir, d.SuggestedFilename), d.Path())
+		received, _ := d.Progress()
+		assert.Equal(t, int64(8), received)
+	}
+	assert.Len(t, m.Downloads(), 2)
+}
+
+func TestCdpHelper_DownloadTo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/report.csv":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", "attachment; filename=report.csv")
+			_, _ = w.Write([]byte("a,b\n1,2\n"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a id="report" href="/report.csv">report</a></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	m, err := b.NewDownloadManager(dir)
+	assert.Nil(t, err)
+	defer m.Close()
+
+	var buf bytes.Buffer
+	filename, err := b.DownloadTo(&buf, func() error {
+		return b.Click(`#report`)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "report.csv", filename)
+	assert.Equal(t, "a,b\n1,2\n", buf.String())
+	assert.Len(t, m.Downloads(), 0)
+
+	// the download behavior of m is restored
+	err = b.Click(`#report`)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	d, err := m.Next(ctx)
+	assert.Nil(t, err)
+	err = d.Wait(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "report.csv"), d.Path())
+}
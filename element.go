@@ -0,0 +1,269 @@
+package cdp_helper
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"time"
+)
+
+// Element is a node located by Query or QueryAll. It stays bound to the tab it was found in, even after the
+// helper switches to another tab, and uses the timeouts the helper had when it was found.
+// An element whose NodeID became stale is found again the way it was located, see Nodes.
+type Element struct {
+	Node *cdp.Node
+
+	helper *CdpHelper
+}
+
+// isVisibleJS tells whether the element is rendered with a non-empty box and isn't hidden
+const isVisibleJS = `function() {
+	const style = window.getComputedStyle(this);
+	const rect = this.getBoundingClientRect();
+	return style.visibility !== "hidden" && style.display !== "none" && rect.width > 0 && rect.height > 0;
+}`
+
+// bound returns a copy of h whose current tab stays the one h has now
+func (h *CdpHelper) bound() *CdpHelper {
+	helper := *h
+	return &helper
+}
+
+// Query waits for the first node matching sel and returns it as an Element
+func (h *CdpHelper) Query(sel any, opts ...chromedp.QueryOption) (*Element, error) {
+	var nodes []*cdp.Node
+	opts = h.queryOpts(sel, opts)
+	queryOpts := append([]chromedp.QueryOption{chromedp.AtLeast(1)}, opts...)
+	if err := h.RunWithTimeout(h.Timeout, chromedp.Nodes(sel, &nodes, queryOpts...)); err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("query %v: %w", sel, ErrNotFound)
+	}
+	h.nodes.remember(nodes[0], &nodeLocator{sel: sel, opts: opts})
+	return &Element{Node: nodes[0], helper: h.bound()}, nil
+}
+
+// QueryAll returns the nodes matching sel as Elements, it doesn't wait and returns none when nothing matches
+func (h *CdpHelper) QueryAll(sel any, opts ...chromedp.QueryOption) ([]*Element, error) {
+	var nodes []*cdp.Node
+	opts = h.queryOpts(sel, opts)
+	queryOpts := append([]chromedp.QueryOption{chromedp.AtLeast(0)}, opts...)
+	if err := h.RunWithTimeout(h.Timeout, chromedp.Nodes(sel, &nodes, queryOpts...)); err != nil {
+		return nil, err
+	}
+	for i, node := range nodes {
+		h.nodes.remember(node, &nodeLocator{sel: sel, opts: opts, index: i})
+	}
+	return h.bound().elements(nodes), nil
+}
+
+func (h *CdpHelper) elements(nodes []*cdp.Node) []*Element {
+	elements := make([]*Element, len(nodes))
+	for i, node := range nodes {
+		elements[i] = &Element{Node: node, helper: h}
+	}
+	return elements
+}
+
+// describe returns the node of nodeID with its NodeID set, DescribeNode leaves it empty
+func describe(ctx context.Context, nodeID cdp.NodeID) (*cdp.Node, error) {
+	node, err := dom.DescribeNode().WithNodeID(nodeID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	node.NodeID = nodeID
+	return node, nil
+}
+
+// run runs fn with a NodeID of the element valid in the current document, within timeout
+func (e *Element) run(timeout time.Duration, fn func(ctx context.Context, nodeID cdp.NodeID) error) error {
+	return e.helper.RunWithTimeout(timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		return e.helper.withNode(ctx, refOf(e.Node), func(nodeID cdp.NodeID) error {
+			return fn(ctx, nodeID)
+		})
+	}))
+}
+
+// call runs the javascript function fn with the node as this, see chromedp.CallFunctionOn for res
+func call(ctx context.Context, nodeID cdp.NodeID, fn string, res any) error {
+	object, err := dom.ResolveNode().WithNodeID(nodeID).Do(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = runtime.ReleaseObject(object.ObjectID).Do(ctx)
+	}()
+
+	return chromedp.CallFunctionOn(fn, res, func(p *runtime.CallFunctionOnParams) *runtime.CallFunctionOnParams {
+		return p.WithObjectID(object.ObjectID)
+	}).Do(ctx)
+}
+
+// Text returns the text content of the element
+func (e *Element) Text() (string, error) {
+	var text string
+	err := e.run(e.helper.TextTimeout, func(ctx context.Context, nodeID cdp.NodeID) error {
+		return chromedp.TextContent([]cdp.NodeID{nodeID}, &text, chromedp.ByNodeID).Do(ctx)
+	})
+	if err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// Attr returns the value of the attribute name and whether the element has it
+func (e *Element) Attr(name string) (string, bool, error) {
+	var attributes []string
+	err := e.run(e.helper.Timeout, func(ctx context.Context, nodeID cdp.NodeID) error {
+		var err error
+		attributes, err = dom.GetAttributes(nodeID).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	for i := 0; i+1 < len(attributes); i += 2 {
+		if attributes[i] == name {
+			return attributes[i+1], true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Click scrolls the element into view and clicks its center
+func (e *Element) Click(opts ...chromedp.MouseOption) error {
+	return e.run(e.helper.Timeout, func(ctx context.Context, nodeID cdp.NodeID) error {
+		return chromedp.MouseClickNode(&cdp.Node{NodeID: nodeID}, opts...).Do(ctx)
+	})
+}
+
+// Type focuses the element and sends the keys of text, once the element is visible
+func (e *Element) Type(text string) error {
+	return e.run(e.helper.Timeout, func(ctx context.Context, nodeID cdp.NodeID) error {
+		return chromedp.SendKeys([]cdp.NodeID{nodeID}, text, chromedp.ByNodeID).Do(ctx)
+	})
+}
+
+// Query returns the first descendant of the element matching the css selector sel, it doesn't wait
+func (e *Element) Query(sel string) (*Element, error) {
+	var node *cdp.Node
+	err := e.run(e.helper.Timeout, func(ctx context.Context, parentID cdp.NodeID) error {
+		nodeID, err := querySelector(ctx, parentID, sel)
+		if err != nil {
+			return err
+		}
+		if nodeID == cdp.EmptyNodeID {
+			return fmt.Errorf("query %q: %w", sel, ErrNotFound)
+		}
+		node, err = describe(ctx, nodeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	parent := refOf(e.Node)
+	e.helper.nodes.remember(node, &nodeLocator{sel: sel, parent: &parent})
+	return &Element{Node: node, helper: e.helper}, nil
+}
+
+// QueryAll returns the descendants of the element matching the css selector sel, it doesn't wait
+func (e *Element) QueryAll(sel string) ([]*Element, error) {
+	var nodes []*cdp.Node
+	err := e.run(e.helper.Timeout, func(ctx context.Context, parentID cdp.NodeID) error {
+		nodeIDs, err := querySelectorAll(ctx, parentID, sel)
+		if err != nil {
+			return err
+		}
+		for _, nodeID := range nodeIDs {
+			node, err := describe(ctx, nodeID)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, node)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	parent := refOf(e.Node)
+	for i, node := range nodes {
+		e.helper.nodes.remember(node, &nodeLocator{sel: sel, parent: &parent, index: i})
+	}
+	return e.helper.elements(nodes), nil
+}
+
+// Parent returns the parent element, ErrNotFound for the root element
+func (e *Element) Parent() (*Element, error) {
+	var node *cdp.Node
+	err := e.run(e.helper.Timeout, func(ctx context.Context, nodeID cdp.NodeID) error {
+		var parent *runtime.RemoteObject
+		if err := call(ctx, nodeID, `function() { return this.parentElement; }`, &parent); err != nil {
+			return err
+		}
+		if parent == nil || parent.ObjectID == "" {
+			return fmt.Errorf("parent of %s: %w", e.Node.LocalName, ErrNotFound)
+		}
+		defer func() {
+			_ = runtime.ReleaseObject(parent.ObjectID).Do(ctx)
+		}()
+
+		parentID, err := dom.RequestNode(parent.ObjectID).Do(ctx)
+		if err != nil {
+			return err
+		}
+		node, err = describe(ctx, parentID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Element{Node: node, helper: e.helper}, nil
+}
+
+// Screenshot captures the element, see CdpHelper.Screenshot for opts
+func (e *Element) Screenshot(opts ...ScreenshotOption) ([]byte, error) {
+	var nodeID cdp.NodeID
+	err := e.run(e.helper.Timeout, func(ctx context.Context, id cdp.NodeID) error {
+		nodeID = id
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e.helper.Screenshot(append(opts, WithNode(&cdp.Node{NodeID: nodeID}))...)
+}
+
+// BoundingBox returns the border box of the element in viewport coordinates, it fails when the element
+// isn't rendered
+func (e *Element) BoundingBox() (*dom.Rect, error) {
+	var model *dom.BoxModel
+	err := e.run(e.helper.Timeout, func(ctx context.Context, nodeID cdp.NodeID) error {
+		var err error
+		model, err = dom.GetBoxModel().WithNodeID(nodeID).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	left, top, right, bottom := quadBounds(model.Border)
+	return &dom.Rect{X: left, Y: top, Width: right - left, Height: bottom - top}, nil
+}
+
+// IsVisible tells whether the element is rendered with a non-empty box and isn't hidden
+func (e *Element) IsVisible() (bool, error) {
+	var visible bool
+	err := e.run(e.helper.Timeout, func(ctx context.Context, nodeID cdp.NodeID) error {
+		return call(ctx, nodeID, isVisibleJS, &visible)
+	})
+	if err != nil {
+		return false, err
+	}
+	return visible, nil
+}
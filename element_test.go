@@ -0,0 +1,96 @@
+package cdp_helper
+
+import (
+	"errors"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCdpHelper_bound(t *testing.T) {
+	first := &ContextWithCancel{}
+	h := &CdpHelper{Current: first, Timeout: time.Second}
+	bound := h.bound()
+
+	h.Current = &ContextWithCancel{}
+	h.Timeout = time.Minute
+	assert.Same(t, first, bound.Current)
+	assert.Equal(t, time.Second, bound.Timeout)
+}
+
+func TestCdpHelper_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body style="margin:0">
+			<form id="login" style="position:absolute;top:10px;left:20px;width:200px;height:100px">
+				<input name="user" value="">
+				<a href="/help" class="link">help</a>
+				<a href="/reset" class="link" style="display:none">reset</a>
+			</form>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	form, err := b.Query(`#login`)
+	assert.Nil(t, err)
+
+	box, err := form.BoundingBox()
+	assert.Nil(t, err)
+	assert.Equal(t, &dom.Rect{X: 20, Y: 10, Width: 200, Height: 100}, box)
+
+	links, err := form.QueryAll(`a.link`)
+	assert.Nil(t, err)
+	assert.Len(t, links, 2)
+	text, err := links[0].Text()
+	assert.Nil(t, err)
+	assert.Equal(t, "help", text)
+	href, ok, err := links[1].Attr("href")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "/reset", href)
+	_, ok, err = links[1].Attr("title")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	visible, err := links[0].IsVisible()
+	assert.Nil(t, err)
+	assert.True(t, visible)
+	visible, err = links[1].IsVisible()
+	assert.Nil(t, err)
+	assert.False(t, visible)
+
+	input, err := form.Query(`input[name=user]`)
+	assert.Nil(t, err)
+	err = input.Type("gopher")
+	assert.Nil(t, err)
+	var value string
+	err = b.Run(chromedp.Evaluate(`document.querySelector("input").value`, &value))
+	assert.Nil(t, err)
+	assert.Equal(t, "gopher", value)
+
+	parent, err := input.Parent()
+	assert.Nil(t, err)
+	id, _, err := parent.Attr("id")
+	assert.Nil(t, err)
+	assert.Equal(t, "login", id)
+
+	_, err = form.Query(`select`)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	all, err := b.QueryAll(`table`)
+	assert.Nil(t, err)
+	assert.Empty(t, all)
+
+	data, err := form.Screenshot()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, data)
+}
@@ -0,0 +1,127 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto"
+	"github.com/chromedp/cdproto/inspector"
+	"github.com/chromedp/chromedp"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	// ErrNotFound means no element, node or tab matched
+	ErrNotFound = errors.New("not found")
+	// ErrTimeout means the operation didn't finish in time, such errors match context.DeadlineExceeded too
+	ErrTimeout = errors.New("timeout")
+	// ErrTargetClosed means the tab was closed or crashed
+	ErrTargetClosed = errors.New("target closed")
+	// ErrStaleNode means a node is no longer in the page and couldn't be found again the way it was located
+	ErrStaleNode = errors.New("stale node")
+)
+
+// NavigationError is returned when a page fails to load, either with a network error
+// like net::ERR_NAME_NOT_RESOLVED in ErrorText or with an unexpected HTTP Status
+type NavigationError struct {
+	URL       string
+	ErrorText string
+	Status    int64
+}
+
+func (e *NavigationError) Error() string {
+	if e.ErrorText != "" {
+		return fmt.Sprintf("navigate to %s: %s", e.URL, e.ErrorText)
+	}
+	return fmt.Sprintf("navigate to %s: status %d", e.URL, e.Status)
+}
+
+// kindError tags err with one of the sentinel errors, keeping its message
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+// navigationError converts the page load error of chromedp.Navigate into a NavigationError
+func navigationError(url string, err error) error {
+	const prefix = "page load error "
+	if err != nil && strings.HasPrefix(err.Error(), prefix) {
+		return &NavigationError{URL: url, ErrorText: strings.TrimPrefix(err.Error(), prefix)}
+	}
+	return err
+}
+
+// watchCrash remembers when the current tab crashes, so the errors that follow are ErrTargetClosed
+func (h *CdpHelper) watchCrash() {
+	h.crashed = &atomic.Bool{}
+	crashed := h.crashed
+	chromedp.ListenTarget(h.Current.Context, func(ev any) {
+		if _, ok := ev.(*inspector.EventTargetCrashed); ok {
+			crashed.Store(true)
+		}
+	})
+}
+
+// classify returns err tagged with ErrNotFound, ErrTimeout or ErrTargetClosed when it recognizes it,
+// an ErrStaleNode is kept as is
+func (h *CdpHelper) classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var navigationErr *NavigationError
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrTimeout) || errors.Is(err, ErrTargetClosed) ||
+		errors.Is(err, ErrStaleNode) || errors.As(err, &navigationErr) {
+		return err
+	}
+
+	kind := errorKind(err)
+	if h.crashed.Load() || h.Current.Context.Err() != nil {
+		kind = ErrTargetClosed
+	}
+	if kind == nil {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+func errorKind(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, chromedp.ErrPollingTimeout):
+		return ErrTimeout
+	case errors.Is(err, chromedp.ErrNoResults):
+		return ErrNotFound
+	case errors.Is(err, chromedp.ErrChannelClosed), errors.Is(err, chromedp.ErrInvalidTarget):
+		return ErrTargetClosed
+	}
+
+	if isStaleNode(err) {
+		return ErrNotFound
+	}
+	var cdpErr *cdproto.Error
+	if !errors.As(err, &cdpErr) {
+		return nil
+	}
+	switch cdpErr.Message {
+	case "Target closed", "No target with given id found", "Session with given id not found":
+		return ErrTargetClosed
+	}
+	return nil
+}
+
+// fail classifies err and saves the artifacts when ArtifactsOnError is enabled
+func (h *CdpHelper) fail(err error) error {
+	return h.withArtifacts(h.classify(err))
+}
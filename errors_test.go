@@ -0,0 +1,81 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestErrorKind(t *testing.T) {
+	assert.Equal(t, ErrTimeout, errorKind(context.DeadlineExceeded))
+	assert.Equal(t, ErrTimeout, errorKind(fmt.Errorf("wait: %w", chromedp.ErrPollingTimeout)))
+	assert.Equal(t, ErrNotFound, errorKind(chromedp.ErrNoResults))
+	assert.Equal(t, ErrNotFound, errorKind(&cdproto.Error{Code: -32000, Message: "Could not find node with given id"}))
+	assert.Equal(t, ErrTargetClosed, errorKind(chromedp.ErrChannelClosed))
+	assert.Equal(t, ErrTargetClosed, errorKind(&cdproto.Error{Code: -32001, Message: "Session with given id not found"}))
+	assert.Nil(t, errorKind(errors.New("boom")))
+}
+
+func TestNavigationError(t *testing.T) {
+	err := navigationError("http://example.invalid", errors.New("page load error net::ERR_NAME_NOT_RESOLVED"))
+	var navigationErr *NavigationError
+	assert.True(t, errors.As(err, &navigationErr))
+	assert.Equal(t, "net::ERR_NAME_NOT_RESOLVED", navigationErr.ErrorText)
+	assert.Equal(t, "navigate to http://example.invalid: net::ERR_NAME_NOT_RESOLVED", err.Error())
+	assert.Equal(t, "navigate to http://example.com: status 404", (&NavigationError{URL: "http://example.com", Status: 404}).Error())
+
+	assert.Nil(t, navigationError("http://example.com", nil))
+	assert.Equal(t, context.Canceled, navigationError("http://example.com", context.Canceled))
+}
+
+func TestCdpHelper_classify(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &CdpHelper{Current: &ContextWithCancel{Context: ctx, Cancel: cancel}, crashed: &atomic.Bool{}}
+
+	err := h.classify(context.DeadlineExceeded)
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, "context deadline exceeded", err.Error())
+	assert.Equal(t, err, h.classify(err))
+
+	boom := errors.New("boom")
+	assert.Equal(t, boom, h.classify(boom))
+	assert.Nil(t, h.classify(nil))
+
+	h.crashed.Store(true)
+	assert.True(t, errors.Is(h.classify(boom), ErrTargetClosed))
+	h.crashed.Store(false)
+	cancel()
+	assert.True(t, errors.Is(h.classify(context.Canceled), ErrTargetClosed))
+}
+
+func TestCdpHelper_TypedErrors(t *testing.T) {
+	b := NewBrowser(true)
+	defer b.Close()
+
+	err := b.Navigate("http://cdp-helper.invalid/")
+	var navigationErr *NavigationError
+	assert.True(t, errors.As(err, &navigationErr))
+	assert.Equal(t, "net::ERR_NAME_NOT_RESOLVED", navigationErr.ErrorText)
+
+	err = b.WaitReadyWithTimeout(200*time.Millisecond, `#missing`)
+	assert.True(t, errors.Is(err, ErrTimeout))
+
+	nodes, err := b.Nodes(`body`)
+	assert.Nil(t, err)
+	_, _, err = b.ChildNode(b.Current.Context, nodes[0].NodeID, `#missing`)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	tab, err := b.NewBlankTab("")
+	assert.Nil(t, err)
+	assert.Nil(t, tab.Close())
+	_, err = tab.Nodes(`body`)
+	assert.True(t, errors.Is(err, ErrTargetClosed))
+}
@@ -0,0 +1,327 @@
+package cdp_helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError is a field Extract couldn't fill, Err is ErrNotFound when a required field matched no node
+type FieldError struct {
+	Field    string // path of the field, e.g. [2].Author.Name
+	Selector string // the selector of the field, or its column header for Table.Decode
+	Err      error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s (%q): %v", e.Field, e.Selector, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// extractField is the spec of a struct field sent to the page, the exported fields are read by extractJS
+type extractField struct {
+	Selector string          `json:"selector,omitempty"`
+	Attr     string          `json:"attr,omitempty"`
+	HTML     bool            `json:"html,omitempty"`
+	Many     bool            `json:"many,omitempty"`
+	Fields   []*extractField `json:"fields,omitempty"`
+
+	name     string
+	index    int
+	optional bool
+	layout   string
+}
+
+// extractJS returns for every node matching the root selector the raw values of the fields,
+// null for a missing node and an array for a slice or a struct
+const extractJS = `((spec) => {
+	const value = (node, f) => {
+		if (f.fields) return f.fields.map(child => extract(node, child));
+		if (f.attr) return node.getAttribute(f.attr);
+		if (f.html) return node.innerHTML;
+		return node.textContent;
+	};
+	const extract = (scope, f) => {
+		if (f.many) return Array.from(f.selector ? scope.querySelectorAll(f.selector) : [scope]).map(node => value(node, f));
+		const node = f.selector ? scope.querySelector(f.selector) : scope;
+		return node ? value(node, f) : null;
+	};
+	return extract(document, spec);
+})(%s)`
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// parseExtractTag parses a tag like "a > span.title, attr=href, optional", the selector may contain commas
+func parseExtractTag(tag string) (*extractField, error) {
+	f := &extractField{layout: time.RFC3339}
+	parts := strings.Split(tag, ",")
+	i := len(parts)
+options:
+	for ; i > 1; i-- {
+		option := strings.TrimSpace(parts[i-1])
+		switch {
+		case option == "text":
+		case option == "html":
+			f.HTML = true
+		case option == "optional":
+			f.optional = true
+		case strings.HasPrefix(option, "attr="):
+			f.Attr = strings.TrimPrefix(option, "attr=")
+			if f.Attr == "" {
+				return nil, fmt.Errorf("empty attribute name in tag %q", tag)
+			}
+		case strings.HasPrefix(option, "layout="):
+			f.layout = strings.TrimPrefix(option, "layout=")
+		default:
+			// the rest is the selector
+			break options
+		}
+	}
+	f.Selector = strings.TrimSpace(strings.Join(parts[:i], ","))
+	return f, nil
+}
+
+// newExtractFields returns the spec of the tagged fields of struct type t, untagged struct fields share
+// the scope of their parent
+func newExtractFields(t reflect.Type) ([]*extractField, error) {
+	return buildExtractFields(t, map[reflect.Type]bool{})
+}
+
+// buildExtractFields is newExtractFields failing on a struct containing itself, building holds the types
+// being built
+func buildExtractFields(t reflect.Type, building map[reflect.Type]bool) ([]*extractField, error) {
+	if building[t] {
+		return nil, fmt.Errorf("recursive type %s", t)
+	}
+	building[t] = true
+	defer delete(building, t)
+
+	var fields []*extractField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup("cdp")
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+
+		typ := sf.Type
+		f := &extractField{layout: time.RFC3339}
+		if tagged {
+			var err error
+			if f, err = parseExtractTag(tag); err != nil {
+				return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+			}
+		} else if typ.Kind() != reflect.Struct || typ == timeType {
+			continue
+		}
+		f.name = sf.Name
+		f.index = i
+
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8 {
+			f.Many = true
+			typ = typ.Elem()
+		}
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ.Kind() == reflect.Struct && typ != timeType {
+			if f.Attr != "" || f.HTML {
+				return nil, fmt.Errorf("field %s: a struct can't take attr or html", sf.Name)
+			}
+			children, err := buildExtractFields(typ, building)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+			}
+			// non-nil, so a struct without located fields is still a struct
+			f.Fields = append([]*extractField{}, children...)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// Extract fills v, a pointer to a slice of structs or to a struct, with the nodes matching the css selector sel,
+// one element per node or the first node. The struct fields are located with tags like
+//
+//	Title string    `cdp:"a > span.title"`              // trimmed text content
+//	Link  string    `cdp:"a, attr=href"`                // attribute
+//	Body  string    `cdp:"div.body, html"`              // inner HTML
+//	Logo  string    `cdp:"img, attr=src, optional"`     // no error when img is missing
+//	Date  time.Time `cdp:"time, layout=2006-01-02"`     // parsed with layout, default is time.RFC3339
+//	Tags  []string  `cdp:"ul.tags > li"`                // one element per matching node
+//	User  User      `cdp:"div.user"`                    // the fields of User are located inside div.user
+//
+// Numbers are parsed after removing the thousands separators. An empty selector locates the node itself.
+// Extract doesn't wait for sel, the fields that couldn't be filled are reported as *FieldError joined together.
+func (h *CdpHelper) Extract(sel string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("extract: v must be a non-nil pointer")
+	}
+	dst := rv.Elem()
+
+	elemType := dst.Type()
+	many := elemType.Kind() == reflect.Slice
+	if many {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("extract: unsupported type %s", dst.Type())
+	}
+
+	fields, err := newExtractFields(elemType)
+	if err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	spec := &extractField{Selector: sel, Many: true, Fields: append([]*extractField{}, fields...)}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	var raw []any
+	if err = h.evaluate(fmt.Sprintf(extractJS, data), &raw); err != nil {
+		return err
+	}
+
+	if !many {
+		if len(raw) == 0 {
+			return fmt.Errorf("extract %q: %w", sel, ErrNotFound)
+		}
+		raw = raw[:1]
+	}
+
+	var errs []error
+	if many {
+		dst.Set(reflect.MakeSlice(dst.Type(), len(raw), len(raw)))
+		for i, item := range raw {
+			assignStruct(dst.Index(i), spec, item, fmt.Sprintf("[%d]", i), &errs)
+		}
+	} else {
+		assignStruct(dst, spec, raw[0], "", &errs)
+	}
+	return errors.Join(errs...)
+}
+
+// assignStruct fills dst, a struct or a pointer to one, with the raw values of the fields of f
+func assignStruct(dst reflect.Value, f *extractField, raw any, path string, errs *[]error) {
+	if dst.Kind() == reflect.Pointer {
+		dst.Set(reflect.New(dst.Type().Elem()))
+		dst = dst.Elem()
+	}
+	values, _ := raw.([]any)
+	for i, field := range f.Fields {
+		var value any
+		if i < len(values) {
+			value = values[i]
+		}
+		assignField(dst.Field(field.index), field, value, path+"."+field.name, errs)
+	}
+}
+
+func assignField(dst reflect.Value, f *extractField, raw any, path string, errs *[]error) {
+	fail := func(err error) {
+		*errs = append(*errs, &FieldError{Field: strings.TrimPrefix(path, "."), Selector: f.Selector, Err: err})
+	}
+
+	if raw == nil {
+		if !f.optional && !f.Many {
+			fail(ErrNotFound)
+		}
+		return
+	}
+
+	if f.Many {
+		items, _ := raw.([]any)
+		dst.Set(reflect.MakeSlice(dst.Type(), len(items), len(items)))
+		for i, item := range items {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			if f.Fields != nil {
+				assignStruct(dst.Index(i), f, item, elemPath, errs)
+				continue
+			}
+			if err := setValue(dst.Index(i), item, f.layout); err != nil {
+				*errs = append(*errs, &FieldError{Field: strings.TrimPrefix(elemPath, "."), Selector: f.Selector, Err: err})
+			}
+		}
+		return
+	}
+
+	if f.Fields != nil {
+		assignStruct(dst, f, raw, path, errs)
+		return
+	}
+	if err := setValue(dst, raw, f.layout); err != nil {
+		fail(err)
+	}
+}
+
+// setValue converts the raw string value of a node to the type of dst
+func setValue(dst reflect.Value, raw any, layout string) error {
+	if dst.Kind() == reflect.Pointer {
+		value := reflect.New(dst.Type().Elem())
+		if err := setValue(value.Elem(), raw, layout); err != nil {
+			return err
+		}
+		dst.Set(value)
+		return nil
+	}
+
+	s, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("unexpected value %v", raw)
+	}
+	s = strings.TrimSpace(s)
+
+	if dst.Type() == timeType {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	number := strings.ReplaceAll(s, ",", "")
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(number, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(number, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(number, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", dst.Type())
+	}
+	return nil
+}
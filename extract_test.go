@@ -0,0 +1,144 @@
+package cdp_helper
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type extractAuthor struct {
+	Name string `cdp:"span.name"`
+	URL  string `cdp:"a, attr=href, optional"`
+}
+
+type extractItem struct {
+	Title   string        `cdp:"h2 > a, text"`
+	Link    string        `cdp:"h2 > a, attr=href"`
+	Score   int           `cdp:"span.score"`
+	Price   float64       `cdp:"span.price, optional"`
+	Date    time.Time     `cdp:"time, attr=datetime, layout=2006-01-02"`
+	Tags    []string      `cdp:"ul.tags > li"`
+	Author  extractAuthor `cdp:"div.author"`
+	Summary *string       `cdp:"p.summary, optional"`
+	skipped string
+}
+
+func TestParseExtractTag(t *testing.T) {
+	f, err := parseExtractTag("a > span.title, text")
+	assert.Nil(t, err)
+	assert.Equal(t, "a > span.title", f.Selector)
+
+	f, err = parseExtractTag("h1, h2, attr=id, optional, layout=2006")
+	assert.Nil(t, err)
+	assert.Equal(t, "h1, h2", f.Selector)
+	assert.Equal(t, "id", f.Attr)
+	assert.True(t, f.optional)
+	assert.Equal(t, "2006", f.layout)
+
+	f, err = parseExtractTag("a[data-attr=x], html")
+	assert.Nil(t, err)
+	assert.Equal(t, "a[data-attr=x]", f.Selector)
+	assert.True(t, f.HTML)
+
+	_, err = parseExtractTag("a, attr=")
+	assert.NotNil(t, err)
+}
+
+type extractComment struct {
+	Text    string           `cdp:"p"`
+	Replies []extractComment `cdp:".reply, optional"`
+}
+
+type extractThread struct {
+	First extractAuthor `cdp:".first"`
+	Last  extractAuthor `cdp:".last"`
+}
+
+func TestNewExtractFields(t *testing.T) {
+	_, err := newExtractFields(reflect.TypeOf(extractComment{}))
+	assert.ErrorContains(t, err, "field Replies: recursive type")
+
+	// a struct used twice isn't recursive
+	fields, err := newExtractFields(reflect.TypeOf(extractThread{}))
+	assert.Nil(t, err)
+	assert.Len(t, fields, 2)
+}
+
+func TestAssignStruct(t *testing.T) {
+	fields, err := newExtractFields(reflect.TypeOf(extractItem{}))
+	assert.Nil(t, err)
+	assert.Len(t, fields, 8)
+	spec := &extractField{Fields: fields}
+
+	var item extractItem
+	var errs []error
+	raw := []any{" Go 1.21 \n", "/go", "1,024", nil, "2023-08-08", []any{"go", " release "}, []any{"gopher", nil}, "notes"}
+	assignStruct(reflect.ValueOf(&item).Elem(), spec, raw, "[0]", &errs)
+	assert.Empty(t, errs)
+	assert.Equal(t, "Go 1.21", item.Title)
+	assert.Equal(t, 1024, item.Score)
+	assert.Equal(t, time.Date(2023, 8, 8, 0, 0, 0, 0, time.UTC), item.Date)
+	assert.Equal(t, []string{"go", "release"}, item.Tags)
+	assert.Equal(t, extractAuthor{Name: "gopher"}, item.Author)
+	assert.Equal(t, "notes", *item.Summary)
+
+	item = extractItem{}
+	errs = nil
+	raw = []any{nil, "/go", "many", nil, "2023-08-08", []any{}, nil, nil}
+	assignStruct(reflect.ValueOf(&item).Elem(), spec, raw, "[1]", &errs)
+	assert.Len(t, errs, 3)
+	var fieldErr *FieldError
+	assert.True(t, errors.As(errs[0], &fieldErr))
+	assert.Equal(t, "[1].Title", fieldErr.Field)
+	assert.True(t, errors.Is(errs[0], ErrNotFound))
+	assert.True(t, errors.As(errs[1], &fieldErr))
+	assert.Equal(t, "[1].Score", fieldErr.Field)
+	assert.True(t, errors.Is(errs[2], ErrNotFound))
+	assert.Nil(t, item.Summary)
+}
+
+func TestCdpHelper_Extract(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body>
+			<article><h2><a href="/a">First</a></h2><span class="score">1,200</span><span class="price">9.5</span>
+				<time datetime="2023-01-02"></time><ul class="tags"><li>x</li><li>y</li></ul>
+				<div class="author"><span class="name">ann</span><a href="/ann">ann</a></div></article>
+			<article><h2><a href="/b">Second</a></h2><span class="score">7</span>
+				<time datetime="2023-02-03"></time><div class="author"><span class="name">bob</span></div></article>
+			<article><span class="score">?</span></article>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	var items []extractItem
+	err = b.Extract(`article`, &items)
+	assert.Len(t, items, 3)
+	assert.Equal(t, "First", items[0].Title)
+	assert.Equal(t, 1200, items[0].Score)
+	assert.Equal(t, 9.5, items[0].Price)
+	assert.Equal(t, []string{"x", "y"}, items[0].Tags)
+	assert.Equal(t, extractAuthor{Name: "ann", URL: "/ann"}, items[0].Author)
+	assert.Equal(t, "bob", items[1].Author.Name)
+	assert.Empty(t, items[1].Tags)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Contains(t, err.Error(), "[2].Title")
+	assert.Contains(t, err.Error(), "[2].Score")
+
+	var first extractItem
+	err = b.Extract(`article`, &first)
+	assert.Nil(t, err)
+	assert.Equal(t, "/a", first.Link)
+
+	err = b.Extract(`section`, &first)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
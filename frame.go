@@ -0,0 +1,366 @@
+package cdp_helper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// frameScope is the frame a helper returned by Frame or FrameByURL runs in
+type frameScope struct {
+	id       cdp.FrameID
+	document *cdp.Node      // the document queries start from, nil for a cross-origin frame driven through its own target
+	sessions *frameSessions // the frame sessions of the tab of a cross-origin frame
+}
+
+// frameSessions are the helpers of the cross-origin frames of a tab, one target session per frame
+type frameSessions struct {
+	mu      sync.Mutex
+	helpers map[cdp.FrameID]*CdpHelper
+}
+
+func newFrameSessions() *frameSessions {
+	return &frameSessions{helpers: make(map[cdp.FrameID]*CdpHelper)}
+}
+
+// drop forgets the helper of frame id if it's still frame
+func (s *frameSessions) drop(id cdp.FrameID, frame *CdpHelper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.helpers[id] == frame {
+		delete(s.helpers, id)
+	}
+}
+
+// Frame returns a helper whose selector based methods, e.g. Click, SendKeys, NodeTextContent and Nodes, run inside
+// the document of the iframe matching sel. It waits for the iframe and its document like WaitReady does.
+// A selector is matched as a css selector, or else as an XPath, within the frame document. An explicit
+// chromedp.BySearch searches the whole tab.
+// A cross-origin frame running in its own process is driven through its own target session.
+// A frame that navigates must be got again. The frame goes away with its tab, closing the helper of a cross-origin
+// frame detaches its target session, closing the helper of a same-origin frame does nothing.
+// The helpers of a cross-origin frame share one target session per tab.
+func (h *CdpHelper) Frame(sel any, opts ...chromedp.QueryOption) (*CdpHelper, error) {
+	var frame *CdpHelper
+	err := h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		var nodes []*cdp.Node
+		if err := chromedp.Nodes(sel, &nodes, h.queryOpts(sel, opts)...).Do(ctx); err != nil {
+			return err
+		}
+		var err error
+		frame, err = h.frameOf(ctx, nodes[0].NodeID)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// FrameByURL returns the helper of the first frame below h whose url matches the regular expression pattern,
+// see Frame. It doesn't wait for the frame to be created.
+func (h *CdpHelper) FrameByURL(pattern string) (*CdpHelper, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var frame *CdpHelper
+	err = h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return err
+		}
+		if h.frame != nil && h.frame.document != nil {
+			tree = subtree(tree, h.frame.id)
+		}
+		if id, ok := matchFrame(tree, re); ok {
+			_, ownerID, err := dom.GetFrameOwner(id).Do(ctx)
+			if err != nil {
+				return err
+			}
+			if ownerID == cdp.EmptyNodeID {
+				return fmt.Errorf("frame %s has no owner node: %w", id, ErrNotFound)
+			}
+			frame, err = h.frameOf(ctx, ownerID)
+			return err
+		}
+
+		// cross-origin frames are missing from the frame tree of their parent
+		infos, err := chromedp.Targets(ctx)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if info.Type != "iframe" || !re.MatchString(info.URL) {
+				continue
+			}
+			// the owner of a frame of another page isn't found
+			_, ownerID, err := dom.GetFrameOwner(cdp.FrameID(info.TargetID)).Do(ctx)
+			if err != nil || ownerID == cdp.EmptyNodeID {
+				continue
+			}
+			owned, err := h.ownsFrame(ctx, tree, ownerID)
+			if err != nil {
+				return err
+			}
+			if owned {
+				frame, err = h.attachFrame(cdp.FrameID(info.TargetID))
+				return err
+			}
+		}
+		return fmt.Errorf("no frame matches %q: %w", pattern, ErrNotFound)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// evaluate evaluates the javascript expression within Timeout, in the frame document for a frame helper
+func (h *CdpHelper) evaluate(expression string, res any) error {
+	if h.frame == nil || h.frame.document == nil {
+		return h.RunWithTimeout(h.Timeout, chromedp.Evaluate(expression, res))
+	}
+	// a function called on the frame document runs in the frame, where document is the frame document
+	return h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		return call(ctx, h.frame.document.NodeID, "function() { return "+expression+"; }", res)
+	}))
+}
+
+// ownsFrame tells whether the iframe ownerID is in the document of h, or of a same-origin frame below it.
+// tree is the tree of the frame of h.
+func (h *CdpHelper) ownsFrame(ctx context.Context, tree *page.FrameTree, ownerID cdp.NodeID) (bool, error) {
+	if h.frame == nil || h.frame.document == nil {
+		// the frame owners got from the session of h are all below it
+		return true, nil
+	}
+
+	documents := []cdp.NodeID{h.frame.document.NodeID}
+	var walk func(tree *page.FrameTree) error
+	walk = func(tree *page.FrameTree) error {
+		for _, child := range tree.ChildFrames {
+			_, childOwnerID, err := dom.GetFrameOwner(child.Frame.ID).Do(ctx)
+			if err != nil {
+				return err
+			}
+			childOwner, err := dom.DescribeNode().WithNodeID(childOwnerID).Do(ctx)
+			if err != nil {
+				return err
+			}
+			if doc := childOwner.ContentDocument; doc != nil {
+				nodeIDs, err := dom.PushNodesByBackendIDsToFrontend([]cdp.BackendNodeID{doc.BackendNodeID}).Do(ctx)
+				if err != nil {
+					return err
+				}
+				documents = append(documents, nodeIDs...)
+			}
+			if err = walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if tree != nil {
+		if err := walk(tree); err != nil {
+			return false, err
+		}
+	}
+
+	for _, doc := range documents {
+		owners, err := dom.QuerySelectorAll(doc, "iframe, frame").Do(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, owner := range owners {
+			if owner == ownerID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// frameXPathJS returns the nodes matching an XPath below this, in document order
+const frameXPathJS = `function() {
+	const result = document.evaluate(%s, this, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+	const nodes = [];
+	for (let i = 0; i < result.snapshotLength; i++) {
+		nodes.push(result.snapshotItem(i));
+	}
+	return nodes;
+}`
+
+// searchFrame is BySearch within the document doc of a frame, sel is a css selector or else an XPath
+func searchFrame(ctx context.Context, doc cdp.NodeID, sel string) ([]cdp.NodeID, error) {
+	nodeIDs, err := dom.QuerySelectorAll(doc, sel).Do(ctx)
+	if err == nil {
+		return nodeIDs, nil
+	}
+
+	data, err := json.Marshal(sel)
+	if err != nil {
+		return nil, err
+	}
+	var nodes *runtime.RemoteObject
+	if err = call(ctx, doc, fmt.Sprintf(frameXPathJS, data), &nodes); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = runtime.ReleaseObject(nodes.ObjectID).Do(ctx)
+	}()
+
+	properties, _, _, _, err := runtime.GetProperties(nodes.ObjectID).WithOwnProperties(true).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeIDs = nil
+	for _, property := range properties {
+		if _, err := strconv.Atoi(property.Name); err != nil || property.Value == nil || property.Value.ObjectID == "" {
+			continue
+		}
+		nodeID, err := dom.RequestNode(property.Value.ObjectID).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	return nodeIDs, nil
+}
+
+// subtree returns the tree of frame id, nil when it isn't in tree
+func subtree(tree *page.FrameTree, id cdp.FrameID) *page.FrameTree {
+	if tree == nil || tree.Frame.ID == id {
+		return tree
+	}
+	for _, child := range tree.ChildFrames {
+		if found := subtree(child, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// matchFrame returns the first frame below the root of tree whose url matches re, depth first
+func matchFrame(tree *page.FrameTree, re *regexp.Regexp) (cdp.FrameID, bool) {
+	if tree == nil {
+		return "", false
+	}
+	for _, child := range tree.ChildFrames {
+		if re.MatchString(child.Frame.URL + child.Frame.URLFragment) {
+			return child.Frame.ID, true
+		}
+		if id, ok := matchFrame(child, re); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// frameOf returns the helper of the frame of the iframe ownerID, once the frame has loaded its document
+func (h *CdpHelper) frameOf(ctx context.Context, ownerID cdp.NodeID) (*CdpHelper, error) {
+	for {
+		owner, err := dom.DescribeNode().WithNodeID(ownerID).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if owner.FrameID == "" {
+			return nil, fmt.Errorf("%s isn't a frame: %w", strings.ToLower(owner.NodeName), ErrNotFound)
+		}
+
+		if doc := owner.ContentDocument; doc != nil {
+			// the initial about:blank document is replaced once src loads
+			src := owner.AttributeValue("src")
+			if doc.DocumentURL != "about:blank" || src == "" || src == "about:blank" {
+				nodeIDs, err := dom.PushNodesByBackendIDsToFrontend([]cdp.BackendNodeID{doc.BackendNodeID}).Do(ctx)
+				if err != nil {
+					return nil, err
+				}
+				if len(nodeIDs) == 1 && nodeIDs[0] != cdp.EmptyNodeID {
+					doc.NodeID = nodeIDs[0]
+					doc.ParentID = ownerID
+					frame := h.bound()
+					frame.frame = &frameScope{id: owner.FrameID, document: doc}
+					return frame, nil
+				}
+			}
+		} else {
+			infos, err := chromedp.Targets(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, info := range infos {
+				if info.Type == "iframe" && info.TargetID == target.ID(owner.FrameID) {
+					return h.attachFrame(owner.FrameID)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// attachFrame returns the helper of the cross-origin frame id, attached to its target once per tab
+func (h *CdpHelper) attachFrame(id cdp.FrameID) (*CdpHelper, error) {
+	h.frames.mu.Lock()
+	defer h.frames.mu.Unlock()
+	if frame := h.frames.helpers[id]; frame != nil && frame.Current.Context.Err() == nil && !frame.crashed.Load() {
+		return frame, nil
+	}
+
+	ctx, cancel := chromedp.NewContext(h.Current.Context, chromedp.WithTargetID(target.ID(id)))
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// cancelling the context of a frame target closes its whole page, Close only detaches the session
+	// and the context is cancelled once the frame is destroyed, or with the tab
+	frame := h.newHelper(ctx, func() {})
+	frame.frame = &frameScope{id: id, sessions: h.frames}
+	h.frames.helpers[id] = frame
+
+	// the session of a frame navigating to another process is detached, the frame must be attached again
+	sessionID := chromedp.FromContext(ctx).Target.SessionID
+	chromedp.ListenBrowser(ctx, func(ev any) {
+		switch ev := ev.(type) {
+		case *target.EventDetachedFromTarget:
+			if ev.SessionID == sessionID {
+				go h.frames.drop(id, frame)
+			}
+		case *target.EventTargetDestroyed:
+			if ev.TargetID == target.ID(id) {
+				go func() {
+					h.frames.drop(id, frame)
+					cancel()
+				}()
+			}
+		}
+	})
+	return frame, nil
+}
+
+// detachFrame detaches the target session of the cross-origin frame helper h
+func (h *CdpHelper) detachFrame() error {
+	h.frame.sessions.drop(h.frame.id, h)
+
+	c := chromedp.FromContext(h.Current.Context)
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+	return target.DetachFromTarget().WithSessionID(c.Target.SessionID).Do(cdp.WithExecutor(timeoutCtx, c.Browser))
+}
@@ -0,0 +1,142 @@
+package cdp_helper
+
+import (
+	"errors"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMatchFrame(t *testing.T) {
+	tree := &page.FrameTree{
+		Frame: &cdp.Frame{ID: "main", URL: "https://example.com/"},
+		ChildFrames: []*page.FrameTree{
+			{Frame: &cdp.Frame{ID: "ads", URL: "https://ads.example.com/"}},
+			{
+				Frame: &cdp.Frame{ID: "app", URL: "https://example.com/app"},
+				ChildFrames: []*page.FrameTree{
+					{Frame: &cdp.Frame{ID: "login", URL: "https://example.com/login", URLFragment: "#form"}},
+				},
+			},
+		},
+	}
+
+	id, ok := matchFrame(tree, regexp.MustCompile(`/login#form$`))
+	assert.True(t, ok)
+	assert.Equal(t, cdp.FrameID("login"), id)
+	_, ok = matchFrame(tree, regexp.MustCompile(`^https://example.com/$`))
+	assert.False(t, ok)
+
+	_, ok = matchFrame(subtree(tree, "ads"), regexp.MustCompile(`login`))
+	assert.False(t, ok)
+	id, ok = matchFrame(subtree(tree, "app"), regexp.MustCompile(`login`))
+	assert.True(t, ok)
+	assert.Equal(t, cdp.FrameID("login"), id)
+	assert.Nil(t, subtree(tree, "missing"))
+}
+
+func TestCdpHelper_queryOpts(t *testing.T) {
+	h := &CdpHelper{}
+	assert.Len(t, h.queryOpts(`a`, nil), 0)
+	h.frame = &frameScope{id: "oopif"}
+	assert.Len(t, h.queryOpts(`a`, nil), 0)
+	h.frame.document = &cdp.Node{NodeID: 1}
+	assert.Len(t, h.queryOpts(`a`, nil), 2)
+}
+
+func TestCdpHelper_Frame(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/login":
+			_, _ = w.Write([]byte(`<html><body><form><input id="user"><button type="button"
+				onclick="document.getElementById('status').textContent = document.getElementById('user').value">sign in</button>
+				<span id="status"></span></form></body></html>`))
+		case "/other":
+			cross := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			_, _ = w.Write([]byte(`<html><body><iframe src="` + cross + `/login?other"></iframe></body></html>`))
+		default:
+			// localhost is another site than 127.0.0.1, so the second frame runs out of process
+			cross := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			_, _ = w.Write([]byte(`<html><body><span id="status">top</span>
+				<iframe id="same" src="/login"></iframe>
+				<iframe id="cross" src="` + cross + `/login?cross"></iframe></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	same, err := b.Frame(`#same`)
+	assert.Nil(t, err)
+	cross, err := b.FrameByURL(`\?cross$`)
+	assert.Nil(t, err)
+
+	for _, frame := range []*CdpHelper{same, cross} {
+		err = frame.SendKeys(`#user`, "gopher")
+		assert.Nil(t, err)
+		err = frame.Click(`button`)
+		assert.Nil(t, err)
+		text, err := frame.NodeTextContent(`#status`)
+		assert.Nil(t, err)
+		assert.Equal(t, "gopher", text)
+		nodes, err := frame.Nodes(`form`)
+		assert.Nil(t, err)
+		assert.Len(t, nodes, 1)
+		// the top page has a #status too, a query leaking out of the frame finds both
+		nodes, err = frame.Nodes(`span#status`)
+		assert.Nil(t, err)
+		assert.Len(t, nodes, 1)
+		text, err = frame.NodeTextContent(`//form/span[@id="status"]`)
+		assert.Nil(t, err)
+		assert.Equal(t, "gopher", text)
+		var statuses []struct {
+			Text string `cdp:""`
+		}
+		err = frame.Extract(`span#status`, &statuses)
+		assert.Nil(t, err)
+		assert.Len(t, statuses, 1)
+		assert.Equal(t, "gopher", statuses[0].Text)
+		assert.Nil(t, frame.Close())
+	}
+
+	// a closed cross-origin frame is attached again, then its session is shared
+	again, err := b.Frame(`#cross`)
+	assert.Nil(t, err)
+	assert.NotSame(t, cross, again)
+	text, err := again.NodeTextContent(`#status`)
+	assert.Nil(t, err)
+	assert.Equal(t, "gopher", text)
+	found, err := b.FrameByURL(`\?cross$`)
+	assert.Nil(t, err)
+	assert.Same(t, again, found)
+	assert.Nil(t, again.Close())
+
+	text, err = b.NodeTextContent(`#status`)
+	assert.Nil(t, err)
+	assert.Equal(t, "top", text)
+
+	_, err = b.Frame(`span`)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = b.FrameByURL(`/missing`)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// the cross-origin frames of another tab aren't below b
+	other, err := b.NewBlankTab("")
+	assert.Nil(t, err)
+	defer other.Close()
+	err = other.Navigate(server.URL + "/other")
+	assert.Nil(t, err)
+	_, err = other.FrameByURL(`\?other$`)
+	assert.Nil(t, err)
+	_, err = b.FrameByURL(`\?other$`)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
@@ -5,6 +5,7 @@ go 1.20
 require (
 	github.com/chromedp/cdproto v0.0.0-20230502002814-67c6147a4636
 	github.com/chromedp/chromedp v0.9.1
+	github.com/mailru/easyjson v0.7.7
 	github.com/stretchr/testify v1.8.2
 )
 
@@ -15,7 +16,6 @@ require (
 	github.com/gobwas/pool v0.2.1 // indirect
 	github.com/gobwas/ws v1.2.0 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
-	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/sys v0.8.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
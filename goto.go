@@ -0,0 +1,212 @@
+package cdp_helper
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"sync"
+	"time"
+)
+
+// WaitUntil is the point a Goto waits for before returning
+type WaitUntil string
+
+const (
+	WaitUntilLoad             WaitUntil = "load"
+	WaitUntilDOMContentLoaded WaitUntil = "DOMContentLoaded"
+	WaitUntilNetworkIdle      WaitUntil = "networkIdle" // the load event, then no request sent since Goto in flight for 500ms
+)
+
+// Redirect is a redirect response met by a Goto
+type Redirect struct {
+	URL      string
+	Status   int64
+	Location string
+}
+
+// Response is the response of the main document loaded by Goto
+type Response struct {
+	// URL is the final url after redirects
+	URL        string
+	Status     int64
+	StatusText string
+	Headers    network.Headers
+	MimeType   string
+	// Redirects are in the order they were followed, the first one is for the requested url
+	Redirects []Redirect
+}
+
+// GotoOption configures Goto
+type GotoOption func(*gotoConfig)
+
+type gotoConfig struct {
+	waitUntil   WaitUntil
+	statusError bool
+}
+
+// WithWaitUntil sets what Goto waits for, default is WaitUntilLoad
+func WithWaitUntil(waitUntil WaitUntil) GotoOption {
+	return func(config *gotoConfig) {
+		config.waitUntil = waitUntil
+	}
+}
+
+// WithStatusError makes Goto return a *NavigationError when the status isn't 2xx
+func WithStatusError() GotoOption {
+	return func(config *gotoConfig) {
+		config.statusError = true
+	}
+}
+
+// navigation collects the document responses and lifecycle events of a target
+type navigation struct {
+	mu        sync.Mutex
+	responses map[network.RequestID]*Response
+	lifecycle map[cdp.LoaderID]map[string]bool
+	changed   chan struct{}
+}
+
+func newNavigation() *navigation {
+	return &navigation{
+		responses: make(map[network.RequestID]*Response),
+		lifecycle: make(map[cdp.LoaderID]map[string]bool),
+		changed:   make(chan struct{}, 1),
+	}
+}
+
+func (n *navigation) handle(ev any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if ev.Type != network.ResourceTypeDocument {
+			return
+		}
+		resp, ok := n.responses[ev.RequestID]
+		if !ok {
+			resp = &Response{}
+			n.responses[ev.RequestID] = resp
+		}
+		resp.URL = ev.Request.URL
+		if ev.RedirectResponse != nil {
+			resp.Redirects = append(resp.Redirects, Redirect{
+				URL:      ev.RedirectResponse.URL,
+				Status:   ev.RedirectResponse.Status,
+				Location: ev.Request.URL,
+			})
+		}
+	case *network.EventResponseReceived:
+		resp, ok := n.responses[ev.RequestID]
+		if !ok || ev.Type != network.ResourceTypeDocument {
+			return
+		}
+		resp.URL = ev.Response.URL
+		resp.Status = ev.Response.Status
+		resp.StatusText = ev.Response.StatusText
+		resp.Headers = ev.Response.Headers
+		resp.MimeType = ev.Response.MimeType
+	case *page.EventLifecycleEvent:
+		events, ok := n.lifecycle[ev.LoaderID]
+		if !ok {
+			events = make(map[string]bool)
+			n.lifecycle[ev.LoaderID] = events
+		}
+		events[ev.Name] = true
+		select {
+		case n.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// wait waits for the lifecycle event name of loaderID
+func (n *navigation) wait(ctx context.Context, loaderID cdp.LoaderID, name string) error {
+	for {
+		n.mu.Lock()
+		fired := n.lifecycle[loaderID][name]
+		n.mu.Unlock()
+		if fired {
+			return nil
+		}
+
+		select {
+		case <-n.changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// response returns the document response of the navigation loaderID, the request id of a navigation is its loader id
+func (n *navigation) response(loaderID cdp.LoaderID) (*Response, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	resp, ok := n.responses[network.RequestID(loaderID)]
+	if !ok || resp.Status == 0 {
+		return nil, false
+	}
+	return resp, true
+}
+
+// Goto navigates the current tab to url within Timeout and returns the response of the main document.
+// Unlike Navigate it reports the status, a url served without HTTP, like about:blank, or a
+// same-document navigation returns a Response with only URL set.
+func (h *CdpHelper) Goto(url string, opts ...GotoOption) (*Response, error) {
+	config := &gotoConfig{waitUntil: WaitUntilLoad}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	listenCtx, listenCancel := context.WithCancel(h.Current.Context)
+	defer listenCancel()
+	nav := newNavigation()
+	chromedp.ListenTarget(listenCtx, nav.handle)
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+
+	since := time.Now()
+	var loaderID cdp.LoaderID
+	err := chromedp.Run(timeoutCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var errorText string
+		var err error
+		_, loaderID, errorText, err = page.Navigate(url).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if errorText != "" {
+			return &NavigationError{URL: url, ErrorText: errorText}
+		}
+		return nil
+	}))
+	if err != nil {
+		return nil, h.fail(err)
+	}
+	if loaderID == "" {
+		return &Response{URL: url}, nil
+	}
+
+	event := string(WaitUntilLoad)
+	if config.waitUntil == WaitUntilDOMContentLoaded {
+		event = string(WaitUntilDOMContentLoaded)
+	}
+	if err = nav.wait(timeoutCtx, loaderID, event); err != nil {
+		return nil, h.fail(err)
+	}
+	if config.waitUntil == WaitUntilNetworkIdle {
+		if err = h.inflight.wait(timeoutCtx, since, defaultIdleFor, defaultMaxInflight); err != nil {
+			return nil, h.fail(err)
+		}
+	}
+
+	resp, ok := nav.response(loaderID)
+	if !ok {
+		return &Response{URL: url}, nil
+	}
+	if config.statusError && (resp.Status < 200 || resp.Status > 299) {
+		return resp, h.fail(&NavigationError{URL: resp.URL, Status: resp.Status})
+	}
+	return resp, nil
+}
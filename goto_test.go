@@ -0,0 +1,86 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNavigation_handle(t *testing.T) {
+	n := newNavigation()
+	n.handle(&network.EventRequestWillBeSent{
+		RequestID: "loader-1",
+		Type:      network.ResourceTypeDocument,
+		Request:   &network.Request{URL: "http://example.com/old"},
+	})
+	n.handle(&network.EventRequestWillBeSent{
+		RequestID:        "loader-1",
+		Type:             network.ResourceTypeDocument,
+		Request:          &network.Request{URL: "http://example.com/new"},
+		RedirectResponse: &network.Response{URL: "http://example.com/old", Status: 301},
+	})
+	n.handle(&network.EventResponseReceived{
+		RequestID: "loader-1",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{URL: "http://example.com/new", Status: 200, StatusText: "OK", MimeType: "text/html"},
+	})
+
+	resp, ok := n.response("loader-1")
+	assert.True(t, ok)
+	assert.Equal(t, "http://example.com/new", resp.URL)
+	assert.Equal(t, int64(200), resp.Status)
+	assert.Equal(t, []Redirect{{URL: "http://example.com/old", Status: 301, Location: "http://example.com/new"}}, resp.Redirects)
+	_, ok = n.response("loader-2")
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, n.wait(ctx, "loader-1", "load"))
+
+	go n.handle(&page.EventLifecycleEvent{LoaderID: cdp.LoaderID("loader-1"), Name: "load"})
+	assert.Nil(t, n.wait(context.Background(), "loader-1", "load"))
+}
+
+func TestCdpHelper_Goto(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("X-Page", "new")
+			_, _ = w.Write([]byte(`<html><body>new</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+
+	resp, err := b.Goto(server.URL + "/old")
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL+"/new", resp.URL)
+	assert.Equal(t, int64(200), resp.Status)
+	assert.Equal(t, "new", resp.Headers["X-Page"])
+	assert.Len(t, resp.Redirects, 1)
+	assert.Equal(t, int64(301), resp.Redirects[0].Status)
+
+	resp, err = b.Goto(server.URL+"/missing", WithWaitUntil(WaitUntilDOMContentLoaded))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(404), resp.Status)
+
+	resp, err = b.Goto(server.URL+"/missing", WithWaitUntil(WaitUntilNetworkIdle), WithStatusError())
+	var navigationErr *NavigationError
+	assert.True(t, errors.As(err, &navigationErr))
+	assert.Equal(t, int64(404), navigationErr.Status)
+	assert.Equal(t, int64(404), resp.Status)
+}
@@ -0,0 +1,434 @@
+package cdp_helper
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type harLog struct {
+	Log harContent `json:"log"`
+}
+
+type harContent struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+	requestTime     float64     // monotonic seconds the request was sent at
+	responseTiming  *network.ResourceTiming
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harBody        `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harBody struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harRecorder records the network events of a tab between StartHAR and StopHAR
+type harRecorder struct {
+	mu        sync.Mutex
+	recording bool
+	bodies    bool
+	entries   []*harEntry
+	pending   map[network.RequestID]*harEntry
+	wg        sync.WaitGroup
+	cancel    context.CancelFunc
+}
+
+// HAROption configures StartHAR
+type HAROption func(*harRecorder)
+
+// WithHARBodies records the response bodies too
+func WithHARBodies() HAROption {
+	return func(r *harRecorder) {
+		r.bodies = true
+	}
+}
+
+// StartHAR starts recording the network events of the current tab, call StopHAR to write them as a HAR 1.2 document
+func (h *CdpHelper) StartHAR(opts ...HAROption) error {
+	r := h.har
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recording {
+		return errors.New("HAR is already recording")
+	}
+
+	r.recording = true
+	r.bodies = false
+	r.entries = nil
+	r.pending = make(map[network.RequestID]*harEntry)
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	var listenCtx context.Context
+	listenCtx, r.cancel = context.WithCancel(h.Current.Context)
+	chromedp.ListenTarget(listenCtx, func(ev any) {
+		r.handle(h, ev)
+	})
+
+	return nil
+}
+
+// StopHAR stops recording and writes the recorded requests to w, unfinished requests are written as they are
+func (h *CdpHelper) StopHAR(w io.Writer) error {
+	r := h.har
+	r.mu.Lock()
+	if !r.recording {
+		r.mu.Unlock()
+		return errors.New("HAR is not recording")
+	}
+	r.recording = false
+	r.cancel()
+	r.mu.Unlock()
+
+	// wait for the bodies being read
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	har := harLog{
+		Log: harContent{
+			Version: "1.2",
+			Creator: harCreator{Name: "cdp-helper", Version: "1.0"},
+			Entries: r.entries,
+		},
+	}
+	if har.Log.Entries == nil {
+		har.Log.Entries = []*harEntry{}
+	}
+	r.entries = nil
+	r.pending = nil
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(har)
+}
+
+func (r *harRecorder) handle(h *CdpHelper, ev any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recording {
+		return
+	}
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if prev, ok := r.pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+			fillHARResponse(prev, ev.RedirectResponse)
+			prev.Response.RedirectURL = ev.Request.URL
+			finishHAREntry(prev, ev.Timestamp)
+			delete(r.pending, ev.RequestID)
+		}
+		entry := newHAREntry(ev)
+		r.entries = append(r.entries, entry)
+		r.pending[ev.RequestID] = entry
+	case *network.EventResponseReceived:
+		if entry, ok := r.pending[ev.RequestID]; ok {
+			fillHARResponse(entry, ev.Response)
+		}
+	case *network.EventLoadingFinished:
+		entry, ok := r.pending[ev.RequestID]
+		if !ok {
+			return
+		}
+		delete(r.pending, ev.RequestID)
+		entry.Response.BodySize = int64(ev.EncodedDataLength)
+		entry.Response.Content.Size = entry.Response.BodySize
+		finishHAREntry(entry, ev.Timestamp)
+		if r.bodies {
+			r.wg.Add(1)
+			go func(id network.RequestID) {
+				defer r.wg.Done()
+				timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+				defer timeoutCancel()
+				body, base64Encoded, err := responseBody(h.NewTargetExecutor(timeoutCtx), id)
+				if err != nil {
+					return
+				}
+
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				entry.Response.Content.Size = int64(len(body))
+				if base64Encoded {
+					entry.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+					entry.Response.Content.Encoding = "base64"
+				} else {
+					entry.Response.Content.Text = string(body)
+				}
+			}(ev.RequestID)
+		}
+	case *network.EventLoadingFailed:
+		entry, ok := r.pending[ev.RequestID]
+		if !ok {
+			return
+		}
+		delete(r.pending, ev.RequestID)
+		entry.Error = ev.ErrorText
+		finishHAREntry(entry, ev.Timestamp)
+	}
+}
+
+func newHAREntry(ev *network.EventRequestWillBeSent) *harEntry {
+	request := ev.Request
+	entry := &harEntry{
+		Request: harRequest{
+			Method:      request.Method,
+			URL:         request.URL + request.URLFragment,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harCookie{},
+			Headers:     harHeaders(request.Headers),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: harResponse{
+			Cookies: []harCookie{},
+			Headers: []harNameValue{},
+			Content: harBody{MimeType: "x-unknown"},
+		},
+		ResourceType: string(ev.Type),
+	}
+	if ev.WallTime != nil {
+		entry.StartedDateTime = ev.WallTime.Time().UTC().Format(time.RFC3339Nano)
+	}
+	if ev.Timestamp != nil {
+		entry.requestTime = monotonicSeconds(ev.Timestamp)
+	}
+
+	if u, err := url.Parse(request.URL); err == nil {
+		for name, values := range u.Query() {
+			for _, value := range values {
+				entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+			}
+		}
+		sort.Slice(entry.Request.QueryString, func(i, j int) bool {
+			return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+		})
+	}
+
+	header := httpHeader(request.Headers)
+	for _, cookie := range (&http.Request{Header: header}).Cookies() {
+		entry.Request.Cookies = append(entry.Request.Cookies, harCookie{Name: cookie.Name, Value: cookie.Value})
+	}
+
+	if request.HasPostData {
+		entry.Request.PostData = &harPostData{
+			MimeType: header.Get("Content-Type"),
+			Text:     request.PostData,
+		}
+		entry.Request.BodySize = int64(len(request.PostData))
+	}
+
+	return entry
+}
+
+func fillHARResponse(entry *harEntry, response *network.Response) {
+	entry.Response.Status = response.Status
+	entry.Response.StatusText = response.StatusText
+	entry.Response.HTTPVersion = harHTTPVersion(response.Protocol)
+	entry.Response.Headers = harHeaders(response.Headers)
+	entry.Response.Content.MimeType = response.MimeType
+	entry.Response.HeadersSize = -1
+	entry.Request.HTTPVersion = entry.Response.HTTPVersion
+	entry.ServerIPAddress = response.RemoteIPAddress
+	entry.responseTiming = response.Timing
+	if response.RequestHeaders != nil {
+		entry.Request.Headers = harHeaders(response.RequestHeaders)
+	}
+
+	header := httpHeader(response.Headers)
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		c := harCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			HTTPOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+		}
+		if !cookie.Expires.IsZero() {
+			c.Expires = cookie.Expires.UTC().Format(time.RFC3339)
+		}
+		entry.Response.Cookies = append(entry.Response.Cookies, c)
+	}
+}
+
+// finishHAREntry computes the timings of entry which ended at end
+func finishHAREntry(entry *harEntry, end *cdp.MonotonicTime) {
+	total := 0.0
+	if end != nil && entry.requestTime > 0 {
+		total = (monotonicSeconds(end) - entry.requestTime) * 1000
+	}
+
+	t := entry.responseTiming
+	if t == nil {
+		entry.Timings = harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Receive: total}
+		entry.Time = total
+		return
+	}
+
+	timings := harTimings{DNS: -1, Connect: -1, SSL: -1}
+	timings.Blocked = firstNonNegative(t.DNSStart, t.ConnectStart, t.SendStart)
+	if t.DNSStart >= 0 {
+		timings.DNS = t.DNSEnd - t.DNSStart
+	}
+	if t.ConnectStart >= 0 {
+		timings.Connect = t.ConnectEnd - t.ConnectStart
+	}
+	if t.SslStart >= 0 {
+		timings.SSL = t.SslEnd - t.SslStart
+	}
+	timings.Send = t.SendEnd - t.SendStart
+	timings.Wait = t.ReceiveHeadersEnd - t.SendEnd
+	if end != nil {
+		timings.Receive = (monotonicSeconds(end)-t.RequestTime)*1000 - t.ReceiveHeadersEnd
+		if timings.Receive < 0 {
+			timings.Receive = 0
+		}
+	}
+
+	entry.Timings = timings
+	entry.Time = 0
+	// ssl is included in connect
+	for _, v := range []float64{timings.Blocked, timings.DNS, timings.Connect, timings.Send, timings.Wait, timings.Receive} {
+		if v > 0 {
+			entry.Time += v
+		}
+	}
+}
+
+func firstNonNegative(values ...float64) float64 {
+	for _, v := range values {
+		if v >= 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+func monotonicSeconds(t *cdp.MonotonicTime) float64 {
+	return float64(t.Time().Sub(*cdp.MonotonicTimeEpoch)) / float64(time.Second)
+}
+
+func harHTTPVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "h2":
+		return "HTTP/2"
+	case "h3", "h3-29", "quic":
+		return "HTTP/3"
+	case "":
+		return "HTTP/1.1"
+	default:
+		return strings.ToUpper(protocol)
+	}
+}
+
+// httpHeader converts CDP headers, where repeated headers are joined by "\n"
+func httpHeader(headers network.Headers) http.Header {
+	header := make(http.Header)
+	for name, value := range headers {
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			header.Add(name, v)
+		}
+	}
+	return header
+}
+
+func harHeaders(headers network.Headers) []harNameValue {
+	values := make([]harNameValue, 0, len(headers))
+	for name, value := range headers {
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			values = append(values, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+	return values
+}
@@ -0,0 +1,101 @@
+package cdp_helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewHAREntry(t *testing.T) {
+	start := cdp.MonotonicTime(cdp.MonotonicTimeEpoch.Add(10 * time.Second))
+	entry := newHAREntry(&network.EventRequestWillBeSent{
+		Request: &network.Request{
+			Method:      "POST",
+			URL:         "https://example.com/search?q=go&page=2",
+			Headers:     network.Headers{"Cookie": "a=1; b=2", "Content-Type": "application/json"},
+			HasPostData: true,
+			PostData:    `{"q":"go"}`,
+		},
+		Timestamp: &start,
+		Type:      network.ResourceTypeFetch,
+	})
+	assert.Equal(t, []harNameValue{{"page", "2"}, {"q", "go"}}, entry.Request.QueryString)
+	assert.Equal(t, []harCookie{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}, entry.Request.Cookies)
+	assert.Equal(t, "application/json", entry.Request.PostData.MimeType)
+	assert.Equal(t, int64(10), entry.Request.BodySize)
+
+	fillHARResponse(entry, &network.Response{
+		Status:   200,
+		Protocol: "h2",
+		Headers:  network.Headers{"Set-Cookie": "c=3; Path=/\nd=4; HttpOnly"},
+		Timing: &network.ResourceTiming{
+			RequestTime:       10,
+			DNSStart:          1,
+			DNSEnd:            3,
+			ConnectStart:      3,
+			ConnectEnd:        8,
+			SslStart:          5,
+			SslEnd:            8,
+			SendStart:         8,
+			SendEnd:           9,
+			ReceiveHeadersEnd: 20,
+		},
+	})
+	assert.Equal(t, "HTTP/2", entry.Response.HTTPVersion)
+	assert.Len(t, entry.Response.Cookies, 2)
+	assert.True(t, entry.Response.Cookies[1].HTTPOnly)
+
+	end := cdp.MonotonicTime(cdp.MonotonicTimeEpoch.Add(10*time.Second + 25*time.Millisecond))
+	finishHAREntry(entry, &end)
+	assert.InDelta(t, 1, entry.Timings.Blocked, 0.001)
+	assert.InDelta(t, 2, entry.Timings.DNS, 0.001)
+	assert.InDelta(t, 5, entry.Timings.Connect, 0.001)
+	assert.InDelta(t, 3, entry.Timings.SSL, 0.001)
+	assert.InDelta(t, 11, entry.Timings.Wait, 0.001)
+	assert.InDelta(t, 5, entry.Timings.Receive, 0.001)
+	assert.InDelta(t, 25, entry.Time, 0.001)
+}
+
+func TestCdpHelper_HAR(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>new</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate("about:blank")
+	assert.Nil(t, err)
+
+	err = b.StartHAR(WithHARBodies())
+	assert.Nil(t, err)
+	assert.NotNil(t, b.StartHAR())
+	err = b.Navigate(server.URL + "/old")
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	err = b.StopHAR(&buf)
+	assert.Nil(t, err)
+	assert.NotNil(t, b.StopHAR(&buf))
+
+	var har harLog
+	err = json.Unmarshal(buf.Bytes(), &har)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2", har.Log.Version)
+	assert.GreaterOrEqual(t, len(har.Log.Entries), 2)
+	assert.Equal(t, int64(302), har.Log.Entries[0].Response.Status)
+	assert.Equal(t, server.URL+"/new", har.Log.Entries[0].Response.RedirectURL)
+	assert.Equal(t, int64(200), har.Log.Entries[1].Response.Status)
+	assert.Contains(t, har.Log.Entries[1].Response.Content.Text, "new")
+}
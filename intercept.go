@@ -0,0 +1,257 @@
+package cdp_helper
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// InterceptedRequest is a request paused by an Intercept route
+type InterceptedRequest struct {
+	ID           fetch.RequestID
+	Request      *network.Request
+	ResourceType network.ResourceType
+}
+
+// InterceptAction decides what happens to an intercepted request, see Continue, ContinueWith, Fulfill and Fail
+type InterceptAction func(ctx context.Context, req *InterceptedRequest) error
+
+// InterceptHandler is called for every request matching an Intercept route, a nil action continues the request
+type InterceptHandler func(req *InterceptedRequest) InterceptAction
+
+// RequestOverride modifies an intercepted request, zero fields are left unchanged
+type RequestOverride struct {
+	URL    string
+	Method string
+	// Headers are merged into the request headers, an empty value removes the header
+	Headers map[string]string
+	Body    []byte
+}
+
+// Continue sends the request unchanged
+func Continue() InterceptAction {
+	return func(ctx context.Context, req *InterceptedRequest) error {
+		return fetch.ContinueRequest(req.ID).Do(ctx)
+	}
+}
+
+// ContinueWith sends the request modified by override
+func ContinueWith(override RequestOverride) InterceptAction {
+	return func(ctx context.Context, req *InterceptedRequest) error {
+		params := fetch.ContinueRequest(req.ID)
+		if override.URL != "" {
+			params = params.WithURL(override.URL)
+		}
+		if override.Method != "" {
+			params = params.WithMethod(override.Method)
+		}
+		if override.Headers != nil {
+			params = params.WithHeaders(mergeHeaders(req.Request.Headers, override.Headers))
+		}
+		if override.Body != nil {
+			params = params.WithPostData(base64.StdEncoding.EncodeToString(override.Body))
+		}
+		return params.Do(ctx)
+	}
+}
+
+// Fulfill answers the request with a canned response, the request never reaches the server
+func Fulfill(status int64, headers map[string]string, body []byte) InterceptAction {
+	return func(ctx context.Context, req *InterceptedRequest) error {
+		return fetch.FulfillRequest(req.ID, status).
+			WithResponseHeaders(mergeHeaders(nil, headers)).
+			WithBody(base64.StdEncoding.EncodeToString(body)).
+			Do(ctx)
+	}
+}
+
+// Fail aborts the request with reason, e.g. network.ErrorReasonBlockedByClient
+func Fail(reason network.ErrorReason) InterceptAction {
+	return func(ctx context.Context, req *InterceptedRequest) error {
+		return fetch.FailRequest(req.ID, reason).Do(ctx)
+	}
+}
+
+func mergeHeaders(headers network.Headers, override map[string]string) []*fetch.HeaderEntry {
+	merged := make(map[string]string)
+	names := make(map[string]string)
+	for name, value := range headers {
+		merged[strings.ToLower(name)] = fmt.Sprint(value)
+		names[strings.ToLower(name)] = name
+	}
+	for name, value := range override {
+		if value == "" {
+			delete(merged, strings.ToLower(name))
+			continue
+		}
+		merged[strings.ToLower(name)] = value
+		names[strings.ToLower(name)] = name
+	}
+
+	entries := make([]*fetch.HeaderEntry, 0, len(merged))
+	for key, value := range merged {
+		entries = append(entries, &fetch.HeaderEntry{Name: names[key], Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+type route struct {
+	pattern      string
+	resourceType network.ResourceType
+	block        bool
+	re           *regexp.Regexp
+	handler      InterceptHandler
+}
+
+// interceptor holds the Intercept routes of a tab
+type interceptor struct {
+	mu     sync.Mutex
+	routes []*route
+	cancel context.CancelFunc
+}
+
+// globToRegexp converts a fetch url pattern, where '*' matches zero or more and '?' exactly one character
+func globToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func (r *route) match(url string, resourceType network.ResourceType) bool {
+	if r.resourceType != "" && r.resourceType != resourceType {
+		return false
+	}
+	return r.re.MatchString(url)
+}
+
+func (i *interceptor) match(url string, resourceType network.ResourceType) *route {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	// blocking routes win, then the latest route
+	for _, r := range i.routes {
+		if r.block && r.match(url, resourceType) {
+			return r
+		}
+	}
+	for j := len(i.routes) - 1; j >= 0; j-- {
+		if i.routes[j].match(url, resourceType) {
+			return i.routes[j]
+		}
+	}
+	return nil
+}
+
+// Intercept pauses the requests of the current tab whose url matches pattern and lets handler decide
+// what to do with them. In pattern '*' matches zero or more characters and '?' exactly one.
+// Call the returned func to remove the route, interception stops when no route is left.
+func (h *CdpHelper) Intercept(pattern string, handler InterceptHandler) (func() error, error) {
+	i := h.interceptor
+	r := &route{
+		pattern: pattern,
+		re:      globToRegexp(pattern),
+		handler: handler,
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.routes = append(i.routes, r)
+	if err := h.applyRoutes(); err != nil {
+		i.routes = i.routes[:len(i.routes)-1]
+		return nil, err
+	}
+
+	var once onceError
+	remove := func() error {
+		return once.Do(func() error {
+			i.mu.Lock()
+			defer i.mu.Unlock()
+			for j, item := range i.routes {
+				if item == r {
+					i.routes = append(i.routes[:j:j], i.routes[j+1:]...)
+					break
+				}
+			}
+			return h.applyRoutes()
+		})
+	}
+
+	return remove, nil
+}
+
+// applyRoutes enables the fetch domain with the patterns of all routes, interceptor.mu must be held
+func (h *CdpHelper) applyRoutes() error {
+	i := h.interceptor
+	if len(i.routes) == 0 {
+		if i.cancel == nil {
+			return nil
+		}
+		i.cancel()
+		i.cancel = nil
+		return h.Run(fetch.Disable())
+	}
+
+	patterns := make([]*fetch.RequestPattern, 0, len(i.routes))
+	for _, r := range i.routes {
+		patterns = append(patterns, &fetch.RequestPattern{URLPattern: r.pattern, ResourceType: r.resourceType})
+	}
+	if err := h.Run(fetch.Enable().WithPatterns(patterns)); err != nil {
+		return err
+	}
+
+	if i.cancel == nil {
+		var listenCtx context.Context
+		listenCtx, i.cancel = context.WithCancel(h.Current.Context)
+		chromedp.ListenTarget(listenCtx, func(ev any) {
+			if ev, ok := ev.(*fetch.EventRequestPaused); ok {
+				go h.handlePaused(listenCtx, ev)
+			}
+		})
+	}
+
+	return nil
+}
+
+func (h *CdpHelper) handlePaused(ctx context.Context, ev *fetch.EventRequestPaused) {
+	req := &InterceptedRequest{
+		ID:           ev.RequestID,
+		Request:      ev.Request,
+		ResourceType: ev.ResourceType,
+	}
+
+	var action InterceptAction
+	if r := h.interceptor.match(ev.Request.URL, ev.ResourceType); r != nil {
+		action = r.handler(req)
+	}
+	if action == nil {
+		action = Continue()
+	}
+
+	_ = action(h.NewTargetExecutor(ctx), req)
+}
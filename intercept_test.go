@@ -0,0 +1,76 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	re := globToRegexp(`*://example.com/api/?/*`)
+	assert.True(t, re.MatchString("https://example.com/api/v/users"))
+	assert.False(t, re.MatchString("https://example.com/api/v1/users"))
+	assert.True(t, globToRegexp(`*.png\?`).MatchString("a.png?"))
+	assert.False(t, globToRegexp(`*.png\?`).MatchString("a.pngx"))
+}
+
+func TestMergeHeaders(t *testing.T) {
+	entries := mergeHeaders(network.Headers{"Accept": "*/*", "Cookie": "a=1"}, map[string]string{
+		"cookie":        "",
+		"Authorization": "Bearer token",
+	})
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "Accept", entries[0].Name)
+	assert.Equal(t, "Authorization", entries[1].Name)
+	assert.Equal(t, "Bearer token", entries[1].Value)
+}
+
+func TestCdpHelper_Intercept(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/user":
+			_, _ = w.Write([]byte(r.Header.Get("X-User")))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><div id="user"></div><div id="mock"></div><script>
+fetch("/api/user").then(r => r.text()).then(t => document.getElementById("user").textContent = t)
+fetch("/api/mock").then(r => r.text()).then(t => document.getElementById("mock").textContent = t)
+</script></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate("about:blank")
+	assert.Nil(t, err)
+
+	removeUser, err := b.Intercept("*/api/user", func(req *InterceptedRequest) InterceptAction {
+		return ContinueWith(RequestOverride{Headers: map[string]string{"X-User": "alice"}})
+	})
+	assert.Nil(t, err)
+	removeMock, err := b.Intercept("*/api/mock", func(req *InterceptedRequest) InterceptAction {
+		return Fulfill(200, map[string]string{"Content-Type": "text/plain"}, []byte("mocked"))
+	})
+	assert.Nil(t, err)
+
+	err = b.NavigateAndWaitIdle(server.URL)
+	assert.Nil(t, err)
+	text, err := b.NodeTextContent(`#user`)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", text)
+	text, err = b.NodeTextContent(`#mock`)
+	assert.Nil(t, err)
+	assert.Equal(t, "mocked", text)
+
+	assert.Nil(t, removeUser())
+	assert.Nil(t, removeMock())
+	assert.Nil(t, removeMock())
+	err = b.NavigateAndWaitIdle(server.URL)
+	assert.Nil(t, err)
+	text, err = b.NodeTextContent(`#user`)
+	assert.Nil(t, err)
+	assert.Empty(t, text)
+}
@@ -0,0 +1,226 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"sync"
+	"time"
+)
+
+const closeTimeout = 10 * time.Second
+
+// session is shared by a root CdpHelper and every tab helper created from it
+type session struct {
+	root     *CdpHelper
+	mu       sync.Mutex
+	tabs     map[*CdpHelper]struct{}
+	registry *tabRegistry
+	blocking blockRules
+	state    *State // loaded into every browser of a pool, see WithState
+	shutdown onceError
+	// the open download managers of every browser context, the last one saves the downloads
+	downloads map[cdp.BrowserContextID][]*DownloadManager
+}
+
+type onceError struct {
+	once sync.Once
+	err  error
+}
+
+func (o *onceError) Do(f func() error) error {
+	o.once.Do(func() {
+		o.err = f()
+	})
+	return o.err
+}
+
+func newSession(root *CdpHelper) *session {
+	return &session{
+		root:      root,
+		tabs:      make(map[*CdpHelper]struct{}),
+		registry:  newTabRegistry(),
+		downloads: make(map[cdp.BrowserContextID][]*DownloadManager),
+	}
+}
+
+func (s *session) add(tab *CdpHelper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tabs[tab] = struct{}{}
+}
+
+func (s *session) remove(tab *CdpHelper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tabs, tab)
+}
+
+// switchedTo tells whether a helper other than tab was switched to the tab of tab
+func (s *session) switchedTo(tab *CdpHelper) bool {
+	for _, helper := range append(s.list(), s.root) {
+		if helper != tab && helper.Current.Context == tab.Current.Context {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *session) list() []*CdpHelper {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tabs := make([]*CdpHelper, 0, len(s.tabs))
+	for tab := range s.tabs {
+		tabs = append(tabs, tab)
+	}
+	return tabs
+}
+
+// newHelper returns a helper sharing h's browser and settings, Current points to ctx
+func (h *CdpHelper) newHelper(ctx context.Context, cancel context.CancelFunc) *CdpHelper {
+	helper := &CdpHelper{
+		Allocator: h.Allocator,
+		Browser:   h.Browser,
+		Current: &ContextWithCancel{
+			Context: ctx,
+			Cancel:  cancel,
+		},
+		Timeout:          h.Timeout,
+		TextTimeout:      h.TextTimeout,
+		DownloadTimeout:  h.DownloadTimeout,
+		EnableScreenshot: h.EnableScreenshot,
+		session:          h.session,
+		closer:           &onceError{},
+		interceptor:      &interceptor{},
+		har:              &harRecorder{},
+		artifacts:        &artifactRecorder{},
+		nodes:            newNodeRegistry(),
+		frames:           newFrameSessions(),
+	}
+	helper.watchCrash()
+	helper.inflight = trackInflight(ctx)
+	return helper
+}
+
+// newTab returns a tab helper sharing h's browser and settings, Current points to ctx
+func (h *CdpHelper) newTab(ctx context.Context, cancel context.CancelFunc) (*CdpHelper, error) {
+	helper := h.newHelper(ctx, cancel)
+	h.session.add(helper)
+	h.artifacts.mu.Lock()
+	dir := h.artifacts.dir
+	h.artifacts.mu.Unlock()
+	helper.ArtifactsOnError(dir)
+	if err := helper.applyBlocking(); err != nil {
+		_ = helper.Close()
+		return nil, err
+	}
+
+	return helper, nil
+}
+
+// IsRoot reports whether h owns the browser rather than being a tab created from it
+func (h *CdpHelper) IsRoot() bool {
+	return h.session.root == h
+}
+
+// Close closes the tab of a tab helper, leaving other tabs open.
+// On the root helper it is equivalent to Shutdown with a 10 seconds timeout, on a frame helper it only detaches
+// the target session of a cross-origin frame. It is idempotent and safe to call concurrently.
+func (h *CdpHelper) Close() error {
+	if h.frame != nil {
+		// a frame goes away with its tab
+		if h.frame.document != nil {
+			return nil
+		}
+		return h.closer.Do(h.detachFrame)
+	}
+	if h.IsRoot() {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+		return h.Shutdown(ctx)
+	}
+	if h.isFirstTab() {
+		return errors.New("the first tab of the browser can't be closed, use Shutdown instead")
+	}
+	if h.session.switchedTo(h) {
+		return errors.New("a helper is switched to the tab, switch it away before closing the tab")
+	}
+
+	return h.closeTab()
+}
+
+// closeTab closes the tab of the tab helper h
+func (h *CdpHelper) closeTab() error {
+	return h.closer.Do(func() error {
+		defer h.session.remove(h)
+		h.session.registry.forget(h.TargetID())
+		return chromedp.Cancel(h.Current.Context)
+	})
+}
+
+// isFirstTab tells whether h drives the tab the browser was started with, which goes away with the browser
+func (h *CdpHelper) isFirstTab() bool {
+	return h.Current.Context == h.session.root.Browser.Context
+}
+
+// Shutdown closes every tab, then closes the browser and waits for chrome to exit
+// and its temporary profile dir to be removed, until ctx is done.
+// Called on a tab helper, it shuts down the whole browser the tab belongs to.
+// It is idempotent and safe to call concurrently.
+func (h *CdpHelper) Shutdown(ctx context.Context) error {
+	root := h.session.root
+	return h.session.shutdown.Do(func() error {
+		var errs []error
+		for _, tab := range h.session.list() {
+			if err := tab.closeTab(); err != nil && !errors.Is(err, context.Canceled) {
+				errs = append(errs, err)
+			}
+		}
+
+		stopped := make(chan error, 1)
+		go func() {
+			stopped <- root.stopBrowser(ctx)
+		}()
+		select {
+		case err := <-stopped:
+			errs = append(errs, err)
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+		}
+		root.Allocator.Cancel()
+
+		return errors.Join(errs...)
+	})
+}
+
+// stopBrowser closes the browser of the root helper h and waits for chrome to exit, until ctx is done
+func (h *CdpHelper) stopBrowser(ctx context.Context) error {
+	if c := chromedp.FromContext(h.Browser.Context); c == nil || c.Browser == nil {
+		// chrome never started or failed to, chromedp.Cancel would wait for it forever
+		h.Browser.Cancel()
+		return nil
+	}
+
+	stopCtx, stop := mergeDone(h.Browser.Context, ctx)
+	defer stop()
+	err := chromedp.Cancel(stopCtx)
+	h.Browser.Cancel()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// mergeDone returns a copy of ctx which is also done when other is done
+func mergeDone(ctx context.Context, other context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-other.Done():
+			cancel()
+		case <-merged.Done():
+		}
+	}()
+	return merged, cancel
+}
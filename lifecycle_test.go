@@ -0,0 +1,72 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnceError(t *testing.T) {
+	var o onceError
+	var calls int
+	for i := 0; i < 3; i++ {
+		err := o.Do(func() error {
+			calls++
+			return errors.New("closed")
+		})
+		assert.EqualError(t, err, "closed")
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestCdpHelper_Close(t *testing.T) {
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate("about:blank")
+	assert.Nil(t, err)
+	tab, err := b.NewBlankTab("")
+	assert.Nil(t, err)
+	assert.False(t, tab.IsRoot())
+	err = tab.Close()
+	assert.Nil(t, err)
+	err = tab.Close()
+	assert.Nil(t, err)
+	err = b.Navigate("about:blank")
+	assert.Nil(t, err)
+}
+
+func TestCdpHelper_Shutdown(t *testing.T) {
+	b := NewBrowser(true)
+	err := b.Navigate("about:blank")
+	assert.Nil(t, err)
+	tab, err := b.NewBlankTab("")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, h := range []*CdpHelper{b, tab, b} {
+		wg.Add(1)
+		go func(h *CdpHelper) {
+			defer wg.Done()
+			assert.Nil(t, h.Shutdown(ctx))
+		}(h)
+	}
+	wg.Wait()
+	assert.NotNil(t, b.Navigate("about:blank"))
+}
+
+func TestCdpHelper_Shutdown_notStarted(t *testing.T) {
+	b := NewBrowserWithOptions(WithAllocatorOptions(chromedp.ExecPath("/nonexistent/chrome")))
+	assert.NotNil(t, b.Run())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	assert.Nil(t, b.Shutdown(ctx))
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,189 @@
+package cdp_helper
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIdleFor     = 500 * time.Millisecond
+	defaultMaxInflight = 0
+)
+
+// finishedRetention is how long a finished request is kept to tell since when the network is idle
+const finishedRetention = time.Minute
+
+// inflightTracker counts the in-flight requests of a target
+type inflightTracker struct {
+	mu       sync.Mutex
+	requests map[network.RequestID]time.Time // the in-flight requests by when they began
+	finished []requestSpan                   // the requests finished within finishedRetention
+	started  time.Time
+	changed  chan struct{}
+}
+
+type requestSpan struct {
+	began, ended time.Time
+}
+
+func newInflightTracker() *inflightTracker {
+	return &inflightTracker{
+		requests: make(map[network.RequestID]time.Time),
+		started:  time.Now(),
+		changed:  make(chan struct{}),
+	}
+}
+
+// trackInflight counts the requests of the target of ctx until ctx is done
+func trackInflight(ctx context.Context) *inflightTracker {
+	t := newInflightTracker()
+	chromedp.ListenTarget(ctx, func(ev any) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			t.add(ev.RequestID)
+		case *network.EventLoadingFinished:
+			t.done(ev.RequestID)
+		case *network.EventLoadingFailed:
+			t.done(ev.RequestID)
+		}
+	})
+	return t
+}
+
+func (t *inflightTracker) add(id network.RequestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// a redirect is sent with the same request id
+	if _, ok := t.requests[id]; !ok {
+		t.requests[id] = time.Now()
+	}
+	t.notify()
+}
+
+func (t *inflightTracker) done(id network.RequestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	began, ok := t.requests[id]
+	if !ok {
+		return
+	}
+	delete(t.requests, id)
+
+	now := time.Now()
+	for len(t.finished) > 0 && now.Sub(t.finished[0].ended) > finishedRetention {
+		t.finished = t.finished[1:]
+	}
+	t.finished = append(t.finished, requestSpan{began: began, ended: now})
+	t.notify()
+}
+
+// notify wakes up every waiter, t.mu must be held
+func (t *inflightTracker) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// idleFor returns how long at most maxInflight of the requests began after since have been in flight,
+// false if more are in flight now, and a channel closed on the next change
+func (t *inflightTracker) idleFor(since time.Time, maxInflight int) (time.Duration, bool, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	var events []event
+	inflight := 0
+	for _, began := range t.requests {
+		if !began.Before(since) {
+			events = append(events, event{began, 1})
+			inflight++
+		}
+	}
+	if inflight > maxInflight {
+		return 0, false, t.changed
+	}
+	for _, span := range t.finished {
+		if !span.began.Before(since) {
+			events = append(events, event{span.began, 1}, event{span.ended, -1})
+		}
+	}
+	// a request beginning and ending at the same time is in flight in between
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta > events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+
+	idleSince := t.started
+	if since.After(idleSince) {
+		idleSince = since
+	}
+	count := 0
+	for _, ev := range events {
+		count += ev.delta
+		// more than maxInflight requests were in flight until now
+		if ev.delta < 0 && count == maxInflight && ev.at.After(idleSince) {
+			idleSince = ev.at
+		}
+	}
+	return time.Since(idleSince), true, t.changed
+}
+
+// wait blocks until at most maxInflight of the requests began after since have been in flight for idleFor
+func (t *inflightTracker) wait(ctx context.Context, since time.Time, idleFor time.Duration, maxInflight int) error {
+	for {
+		idle, ok, changed := t.idleFor(since, maxInflight)
+		if ok && idle >= idleFor {
+			return nil
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if ok {
+			timer = time.NewTimer(idleFor - idle)
+			timeout = timer.C
+		}
+
+		select {
+		case <-changed:
+		case <-timeout:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// WaitNetworkIdle waits until at most maxInflight requests have been in flight for idleFor, within Timeout.
+// The requests which began within idleFor before the call count, so the requests sent by a preceding Click do.
+// Older requests are ignored, otherwise a long-poll, an EventSource or a stream opened earlier would never let
+// the network be idle.
+func (h *CdpHelper) WaitNetworkIdle(idleFor time.Duration, maxInflight int) error {
+	since := time.Now().Add(-idleFor)
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+	return h.fail(h.inflight.wait(timeoutCtx, since, idleFor, maxInflight))
+}
+
+// NavigateAndWaitIdle navigates to url, then waits until no request sent since the navigation has been in flight
+// for 500ms within Timeout
+func (h *CdpHelper) NavigateAndWaitIdle(url string) error {
+	since := time.Now()
+	if err := h.Navigate(url); err != nil {
+		return err
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+	return h.fail(h.inflight.wait(timeoutCtx, since, defaultIdleFor, defaultMaxInflight))
+}
@@ -0,0 +1,100 @@
+package cdp_helper
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInflightTracker(t *testing.T) {
+	tracker := newInflightTracker()
+	tracker.add("1")
+	tracker.add("2")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tracker.done("1")
+		time.Sleep(50 * time.Millisecond)
+		tracker.done("2")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	err := tracker.wait(ctx, start.Add(-100*time.Millisecond), 100*time.Millisecond, 0)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	tracker.add("3")
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = tracker.wait(ctx, start, 10*time.Millisecond, 0)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	err = tracker.wait(context.Background(), start, 10*time.Millisecond, 1)
+	assert.Nil(t, err)
+}
+
+func TestInflightTracker_shortRequest(t *testing.T) {
+	tracker := newInflightTracker()
+	time.Sleep(100 * time.Millisecond)
+	// the request comes and goes before anyone waits
+	tracker.add("1")
+	tracker.done("1")
+
+	start := time.Now()
+	err := tracker.wait(context.Background(), start.Add(-100*time.Millisecond), 100*time.Millisecond, 0)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestInflightTracker_longRequest(t *testing.T) {
+	tracker := newInflightTracker()
+	// a stream which never ends
+	tracker.add("stream")
+	time.Sleep(100 * time.Millisecond)
+	tracker.add("1")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tracker.done("1")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	err := tracker.wait(ctx, start.Add(-50*time.Millisecond), 50*time.Millisecond, 0)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = tracker.wait(ctx, tracker.started, 50*time.Millisecond, 0)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestCdpHelper_NavigateAndWaitIdle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(time.Second)
+			_, _ = w.Write([]byte(`loaded`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><div id="data"></div><script>
+fetch("/slow").then(r => r.text()).then(t => document.getElementById("data").textContent = t)
+</script></body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate("about:blank")
+	assert.Nil(t, err)
+	b.WithTimeout(5 * time.Second)
+	err = b.NavigateAndWaitIdle(server.URL)
+	assert.Nil(t, err)
+	text, err := b.NodeTextContent(`#data`)
+	assert.Nil(t, err)
+	assert.Equal(t, "loaded", text)
+}
@@ -0,0 +1,184 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/chromedp"
+	"sync"
+)
+
+// maxNodeLocators bounds the nodes a tab remembers, the oldest half is forgotten past it
+const maxNodeLocators = 10000
+
+// nodeRef is a node by its ids, without the subtree a *cdp.Node holds
+type nodeRef struct {
+	nodeID    cdp.NodeID
+	backendID cdp.BackendNodeID
+	name      string // the local name, for errors
+}
+
+func refOf(node *cdp.Node) nodeRef {
+	return nodeRef{nodeID: node.NodeID, backendID: node.BackendNodeID, name: node.LocalName}
+}
+
+// nodeLocator is how a node was found, to find it again once its NodeID is stale
+type nodeLocator struct {
+	sel    any // the selector of Nodes, or the css selector of a child of parent
+	opts   []chromedp.QueryOption
+	parent *nodeRef
+	index  int
+
+	nodeID    cdp.NodeID // the NodeID found again, replacing the one of the node
+	backendID cdp.BackendNodeID
+}
+
+// nodeRegistry keeps the locators of the nodes of a tab by the BackendNodeID they were found with,
+// which stays the same while the NodeIDs of the node change
+type nodeRegistry struct {
+	mu       sync.Mutex
+	locators map[cdp.BackendNodeID]*nodeLocator
+	order    []cdp.BackendNodeID
+}
+
+func newNodeRegistry() *nodeRegistry {
+	return &nodeRegistry{locators: make(map[cdp.BackendNodeID]*nodeLocator)}
+}
+
+// remember records how node was found, replacing what was known about it
+func (r *nodeRegistry) remember(node *cdp.Node, locator *nodeLocator) {
+	if r == nil || node.BackendNodeID == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.add(node.BackendNodeID, locator)
+}
+
+// add is remember with r.mu held
+func (r *nodeRegistry) add(key cdp.BackendNodeID, locator *nodeLocator) {
+	if _, ok := r.locators[key]; !ok {
+		r.order = append(r.order, key)
+	}
+	r.locators[key] = locator
+
+	if len(r.order) > maxNodeLocators {
+		forget := r.order[:len(r.order)/2]
+		for _, key := range forget {
+			delete(r.locators, key)
+		}
+		r.order = append([]cdp.BackendNodeID{}, r.order[len(forget):]...)
+	}
+}
+
+// current returns the NodeID and BackendNodeID node is known by now, and its locator if any
+func (r *nodeRegistry) current(node nodeRef) (cdp.NodeID, cdp.BackendNodeID, *nodeLocator) {
+	if r == nil {
+		return node.nodeID, node.backendID, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	locator := r.locators[node.backendID]
+	if locator == nil || locator.nodeID == cdp.EmptyNodeID {
+		return node.nodeID, node.backendID, locator
+	}
+	return locator.nodeID, locator.backendID, locator
+}
+
+// update records the ids node was found again with
+func (r *nodeRegistry) update(node nodeRef, nodeID cdp.NodeID, backendID cdp.BackendNodeID) {
+	if r == nil || node.backendID == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	locator := r.locators[node.backendID]
+	if locator == nil {
+		locator = &nodeLocator{}
+		r.add(node.backendID, locator)
+	}
+	locator.nodeID = nodeID
+	locator.backendID = backendID
+}
+
+// isStaleNode tells whether err is the browser rejecting a NodeID it no longer knows
+func isStaleNode(err error) bool {
+	var cdpErr *cdproto.Error
+	if !errors.As(err, &cdpErr) {
+		return false
+	}
+	switch cdpErr.Message {
+	case "No node with given id found", "Could not find node with given id", "No node found for given backend id":
+		return true
+	}
+	return false
+}
+
+// withNode runs fn with the NodeID node is known by. When the browser rejects it as stale, the node is found
+// again by its BackendNodeID when the DOM was only re-requested, or by the way it was located when the page
+// re-rendered it, and fn runs once more; otherwise it fails with ErrStaleNode. ctx must carry the target executor.
+func (h *CdpHelper) withNode(ctx context.Context, node nodeRef, fn func(nodeID cdp.NodeID) error) error {
+	nodeID, _, _ := h.nodes.current(node)
+	if nodeID != cdp.EmptyNodeID {
+		if err := fn(nodeID); !isStaleNode(err) {
+			return err
+		}
+	}
+
+	nodeID, err := h.relocate(ctx, node)
+	if err != nil {
+		return err
+	}
+	return fn(nodeID)
+}
+
+// relocate finds a stale node again, see withNode
+func (h *CdpHelper) relocate(ctx context.Context, node nodeRef) (cdp.NodeID, error) {
+	_, backendID, locator := h.nodes.current(node)
+	if backendID != 0 {
+		nodeIDs, err := dom.PushNodesByBackendIDsToFrontend([]cdp.BackendNodeID{backendID}).Do(ctx)
+		if err == nil && len(nodeIDs) == 1 && nodeIDs[0] != cdp.EmptyNodeID {
+			h.nodes.update(node, nodeIDs[0], backendID)
+			return nodeIDs[0], nil
+		}
+	}
+
+	if locator == nil || locator.sel == nil {
+		return 0, fmt.Errorf("node %s: %w", node.name, ErrStaleNode)
+	}
+
+	var nodeIDs []cdp.NodeID
+	if locator.parent != nil {
+		err := h.withNode(ctx, *locator.parent, func(parentID cdp.NodeID) error {
+			var err error
+			nodeIDs, err = querySelectorAll(ctx, parentID, locator.sel.(string))
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		var nodes []*cdp.Node
+		opts := append(append([]chromedp.QueryOption{}, locator.opts...), chromedp.AtLeast(0))
+		if err := chromedp.Nodes(locator.sel, &nodes, opts...).Do(ctx); err != nil {
+			return 0, err
+		}
+		for _, n := range nodes {
+			nodeIDs = append(nodeIDs, n.NodeID)
+		}
+	}
+	if locator.index >= len(nodeIDs) {
+		return 0, fmt.Errorf("node %v: %w", locator.sel, ErrStaleNode)
+	}
+
+	nodeID := nodeIDs[locator.index]
+	described, err := dom.DescribeNode().WithNodeID(nodeID).Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	h.nodes.update(node, nodeID, described.BackendNodeID)
+	return nodeID, nil
+}
@@ -0,0 +1,106 @@
+package cdp_helper
+
+import (
+	"errors"
+	"github.com/chromedp/cdproto"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNodeRegistry(t *testing.T) {
+	r := newNodeRegistry()
+	node := &cdp.Node{NodeID: 1, BackendNodeID: 10}
+	nodeID, backendID, locator := r.current(refOf(node))
+	assert.Equal(t, cdp.NodeID(1), nodeID)
+	assert.Equal(t, cdp.BackendNodeID(10), backendID)
+	assert.Nil(t, locator)
+
+	r.remember(node, &nodeLocator{sel: "li", index: 2})
+	r.update(refOf(node), 5, 50)
+	nodeID, backendID, locator = r.current(refOf(node))
+	assert.Equal(t, cdp.NodeID(5), nodeID)
+	assert.Equal(t, cdp.BackendNodeID(50), backendID)
+	assert.Equal(t, 2, locator.index)
+	// another *cdp.Node of the same node shares its locator
+	_, _, same := r.current(refOf(&cdp.Node{NodeID: 7, BackendNodeID: 10}))
+	assert.Same(t, locator, same)
+
+	// a node without a BackendNodeID can't be found again
+	r.remember(&cdp.Node{NodeID: 2}, &nodeLocator{sel: "a"})
+	assert.Len(t, r.locators, 1)
+
+	for i := 0; i < maxNodeLocators; i++ {
+		r.remember(&cdp.Node{BackendNodeID: cdp.BackendNodeID(100 + i)}, &nodeLocator{})
+	}
+	_, _, locator = r.current(refOf(node))
+	assert.Nil(t, locator)
+	assert.LessOrEqual(t, len(r.locators), maxNodeLocators)
+	assert.Len(t, r.order, len(r.locators))
+
+	var none *nodeRegistry
+	none.remember(node, &nodeLocator{})
+	nodeID, _, _ = none.current(refOf(node))
+	assert.Equal(t, cdp.NodeID(1), nodeID)
+}
+
+func TestIsStaleNode(t *testing.T) {
+	assert.True(t, isStaleNode(&cdproto.Error{Code: -32000, Message: "Could not find node with given id"}))
+	assert.False(t, isStaleNode(&cdproto.Error{Code: -32000, Message: "Target closed"}))
+	assert.False(t, isStaleNode(errors.New("Could not find node with given id")))
+}
+
+func TestCdpHelper_staleNodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><ul>
+			<li><span>first</span><button onclick="this.textContent='clicked'">go</button></li>
+			<li><span>second</span></li>
+		</ul></body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	items, err := b.Nodes(`ul > li`)
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+	element, err := b.Query(`ul > li:nth-child(2)`)
+	assert.Nil(t, err)
+
+	err = b.Run(chromedp.Evaluate(`document.querySelector("ul").innerHTML =
+		"<li><span>first again</span><button onclick=\"this.textContent='clicked'\">go</button></li><li><span>second again</span></li>"`, nil))
+	assert.Nil(t, err)
+
+	text, err := b.ChildNodeTextContent(items[1], `span`)
+	assert.Nil(t, err)
+	assert.Equal(t, "second again", text)
+	_, ok := b.HasChildNode(items[0], `button`)
+	assert.True(t, ok)
+	err = b.ClickChild(items[0], `button`)
+	assert.Nil(t, err)
+	text, err = b.ChildNodeTextContent(items[0], `button`)
+	assert.Nil(t, err)
+	assert.Equal(t, "clicked", text)
+
+	err = b.Navigate(server.URL)
+	assert.Nil(t, err)
+	text, err = element.Text()
+	assert.Nil(t, err)
+	assert.Equal(t, "second", text)
+
+	err = b.Run(chromedp.Evaluate(`document.querySelector("ul").remove()`, nil))
+	assert.Nil(t, err)
+	_, err = b.ChildNodeTextContent(items[1], `span`)
+	assert.True(t, errors.Is(err, ErrStaleNode))
+	_, err = element.Text()
+	assert.True(t, errors.Is(err, ErrStaleNode))
+	_, ok = b.HasChildNode(items[0], `button`)
+	assert.False(t, ok)
+}
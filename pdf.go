@@ -0,0 +1,150 @@
+package cdp_helper
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/chromedp/cdproto/cdp"
+	cdpio "github.com/chromedp/cdproto/io"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"io"
+)
+
+// PaperSize is a paper size in inches
+type PaperSize struct {
+	Width  float64
+	Height float64
+}
+
+var (
+	PaperLetter = PaperSize{Width: 8.5, Height: 11}
+	PaperLegal  = PaperSize{Width: 8.5, Height: 14}
+	PaperA3     = PaperSize{Width: 11.69, Height: 16.54}
+	PaperA4     = PaperSize{Width: 8.27, Height: 11.69}
+	PaperA5     = PaperSize{Width: 5.83, Height: 8.27}
+)
+
+// PDFOption configures PDF
+type PDFOption func(*pdfConfig)
+
+type pdfConfig struct {
+	params *page.PrintToPDFParams
+}
+
+// WithPaperSize sets the paper size, default is PaperLetter
+func WithPaperSize(size PaperSize) PDFOption {
+	return func(config *pdfConfig) {
+		config.params = config.params.WithPaperWidth(size.Width).WithPaperHeight(size.Height)
+	}
+}
+
+// WithMargins sets the margins in inches, default is about 0.4 inch on every side
+func WithMargins(top, right, bottom, left float64) PDFOption {
+	return func(config *pdfConfig) {
+		config.params = config.params.
+			WithMarginTop(top).
+			WithMarginRight(right).
+			WithMarginBottom(bottom).
+			WithMarginLeft(left)
+	}
+}
+
+// WithLandscape prints in landscape orientation
+func WithLandscape() PDFOption {
+	return func(config *pdfConfig) {
+		config.params = config.params.WithLandscape(true)
+	}
+}
+
+// WithHeaderFooter prints header and footer, HTML templates which may use the classes
+// date, title, url, pageNumber and totalPages to inject the values, e.g. <span class="pageNumber"></span>.
+// An empty template prints nothing in its place.
+func WithHeaderFooter(header, footer string) PDFOption {
+	return func(config *pdfConfig) {
+		if header == "" {
+			header = "<span></span>"
+		}
+		if footer == "" {
+			footer = "<span></span>"
+		}
+		config.params = config.params.
+			WithDisplayHeaderFooter(true).
+			WithHeaderTemplate(header).
+			WithFooterTemplate(footer)
+	}
+}
+
+// WithPageRanges prints only the given 1-based pages, e.g. "1-5, 8, 11-13"
+func WithPageRanges(ranges string) PDFOption {
+	return func(config *pdfConfig) {
+		config.params = config.params.WithPageRanges(ranges)
+	}
+}
+
+// WithPrintBackground prints the background colors and images
+func WithPrintBackground() PDFOption {
+	return func(config *pdfConfig) {
+		config.params = config.params.WithPrintBackground(true)
+	}
+}
+
+// WithCSSPageSize prefers the page size declared by the css @page rule over the paper size
+func WithCSSPageSize() PDFOption {
+	return func(config *pdfConfig) {
+		config.params = config.params.WithPreferCSSPageSize(true)
+	}
+}
+
+// PDF prints the current tab to w within Timeout, the document is streamed from chrome in chunks
+func (h *CdpHelper) PDF(w io.Writer, opts ...PDFOption) error {
+	config := &pdfConfig{params: page.PrintToPDF()}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+
+	err := chromedp.Run(timeoutCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, stream, err := config.params.
+			WithTransferMode(page.PrintToPDFTransferModeReturnAsStream).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+		defer cdpio.Close(stream).Do(ctx)
+
+		for {
+			data, eof, err := readStream(ctx, stream)
+			if err != nil {
+				return err
+			}
+			if _, err = w.Write(data); err != nil {
+				return err
+			}
+			if eof {
+				return nil
+			}
+		}
+	}))
+	return h.fail(err)
+}
+
+// readStream reads the next chunk of stream, decoding it when chrome sent it as base64
+func readStream(ctx context.Context, stream cdpio.StreamHandle) ([]byte, bool, error) {
+	var res cdpio.ReadReturns
+	err := cdp.Execute(ctx, cdpio.CommandRead, cdpio.Read(stream), &res)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !res.Base64encoded {
+		return []byte(res.Data), res.EOF, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(res.Data)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, res.EOF, nil
+}
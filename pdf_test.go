@@ -0,0 +1,56 @@
+package cdp_helper
+
+import (
+	"bytes"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPDFOptions(t *testing.T) {
+	config := &pdfConfig{params: page.PrintToPDF()}
+	for _, opt := range []PDFOption{
+		WithPaperSize(PaperA4),
+		WithMargins(1, 0.5, 1, 0.5),
+		WithLandscape(),
+		WithHeaderFooter("", `<span class="pageNumber"></span>`),
+		WithPageRanges("1-2"),
+		WithPrintBackground(),
+		WithCSSPageSize(),
+	} {
+		opt(config)
+	}
+
+	p := config.params
+	assert.Equal(t, 8.27, p.PaperWidth)
+	assert.Equal(t, 11.69, p.PaperHeight)
+	assert.Equal(t, []float64{1, 0.5, 1, 0.5}, []float64{p.MarginTop, p.MarginRight, p.MarginBottom, p.MarginLeft})
+	assert.True(t, p.Landscape)
+	assert.True(t, p.DisplayHeaderFooter)
+	assert.Equal(t, "<span></span>", p.HeaderTemplate)
+	assert.Equal(t, `<span class="pageNumber"></span>`, p.FooterTemplate)
+	assert.Equal(t, "1-2", p.PageRanges)
+	assert.True(t, p.PrintBackground)
+	assert.True(t, p.PreferCSSPageSize)
+}
+
+func TestCdpHelper_PDF(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><h1>Invoice</h1><p style="page-break-before:always">page 2</p></body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = b.PDF(&buf, WithPaperSize(PaperA4), WithPrintBackground(), WithPageRanges("1"))
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+	assert.Contains(t, buf.String(), "%%EOF")
+}
@@ -0,0 +1,286 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by Acquire after the pool is closed
+var ErrPoolClosed = errors.New("browser pool is closed")
+
+// replaceRetry is how long a pool waits before trying again to replace a helper
+const replaceRetry = time.Second
+
+// BrowserPool keeps a bounded set of warm helpers, each job leases one helper at a time
+type BrowserPool struct {
+	AcquireTimeout time.Duration // max time to wait for an idle helper
+
+	create   func() (*CdpHelper, error)
+	recreate bool // whether a released helper is replaced rather than reset
+	idle     chan *CdpHelper
+	mu       sync.Mutex
+	origins  map[*CdpHelper]map[string]struct{} // the helpers of the pool and the origins they visited
+	closed   bool
+}
+
+// NewTabPool returns a pool of size tabs of h's browser, each tab in its own isolated context.
+// A released tab is closed with its browser context, and replaced by a tab in a new one.
+func NewTabPool(h *CdpHelper, size int) (*BrowserPool, error) {
+	return newBrowserPool(size, true, func() (*CdpHelper, error) {
+		return h.NewIsolatedContext()
+	})
+}
+
+// NewBrowserPool returns a pool of size browsers, each created with opts.
+// A released browser is reset, the cookies and the storage of every origin it navigated to are cleared.
+func NewBrowserPool(size int, opts ...BrowserOption) (*BrowserPool, error) {
+	return newBrowserPool(size, false, func() (*CdpHelper, error) {
+		helper, err := StartBrowser(opts...)
+		if err != nil {
+			return nil, err
+		}
+		if err = helper.Navigate("about:blank"); err != nil {
+			_ = helper.Close()
+			return nil, err
+		}
+		return helper, nil
+	})
+}
+
+func newBrowserPool(size int, recreate bool, create func() (*CdpHelper, error)) (*BrowserPool, error) {
+	pool := &BrowserPool{
+		AcquireTimeout: 60 * time.Second,
+		create:         create,
+		recreate:       recreate,
+		idle:           make(chan *CdpHelper, size),
+		origins:        make(map[*CdpHelper]map[string]struct{}),
+	}
+
+	for i := 0; i < size; i++ {
+		helper, err := pool.add()
+		if err != nil {
+			_ = pool.Close()
+			return nil, err
+		}
+		pool.idle <- helper
+	}
+
+	return pool, nil
+}
+
+// add creates a helper and tracks the origins it navigates to
+func (p *BrowserPool) add() (*CdpHelper, error) {
+	helper, err := p.create()
+	if err != nil {
+		return nil, err
+	}
+
+	origins := make(map[string]struct{})
+	if !p.recreate {
+		chromedp.ListenTarget(helper.Current.Context, func(ev any) {
+			if ev, ok := ev.(*page.EventFrameNavigated); ok {
+				if origin := originOf(ev.Frame.URL); origin != "" {
+					p.mu.Lock()
+					origins[origin] = struct{}{}
+					p.mu.Unlock()
+				}
+			}
+		})
+	}
+
+	p.mu.Lock()
+	p.origins[helper] = origins
+	p.mu.Unlock()
+
+	return helper, nil
+}
+
+func (p *BrowserPool) remove(helper *CdpHelper) {
+	p.mu.Lock()
+	delete(p.origins, helper)
+	p.mu.Unlock()
+	_ = helper.Close()
+}
+
+// put gives helper back to the idle helpers, or closes it once the pool is closed
+func (p *BrowserPool) put(helper *CdpHelper) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		go p.remove(helper)
+		return
+	}
+	p.idle <- helper
+}
+
+// replace adds a helper in place of a removed one, it tries again every replaceRetry until the pool is closed
+func (p *BrowserPool) replace() {
+	for {
+		helper, err := p.add()
+		if err == nil {
+			p.put(helper)
+			return
+		}
+
+		p.mu.Lock()
+		closed := p.closed
+		p.mu.Unlock()
+		if closed {
+			return
+		}
+		time.Sleep(replaceRetry)
+	}
+}
+
+// Acquire leases an idle helper, it must be given back by Release
+func (p *BrowserPool) Acquire(ctx context.Context) (*CdpHelper, error) {
+	select {
+	case helper, ok := <-p.idle:
+		if !ok {
+			return nil, ErrPoolClosed
+		}
+		return helper, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release resets helper and puts it back to the pool.
+// A crashed or unhealthy helper, or a tab of a tab pool, is replaced by a new one in the background.
+func (p *BrowserPool) Release(helper *CdpHelper) {
+	p.mu.Lock()
+	_, ok := p.origins[helper]
+	closed := p.closed
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+	if closed {
+		p.remove(helper)
+		return
+	}
+
+	if p.recreate || helper.crashed.Load() || p.reset(helper) != nil {
+		p.remove(helper)
+		go p.replace()
+		return
+	}
+	p.put(helper)
+}
+
+// reset health-checks helper and clears the cookies, and the storage of the origins visited during the last lease,
+// then loads the state of WithState again
+func (p *BrowserPool) reset(helper *CdpHelper) error {
+	err := helper.RunWithTimeout(helper.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		var location string
+		if err := chromedp.Location(&location).Do(ctx); err != nil {
+			return err
+		}
+
+		p.mu.Lock()
+		visited := p.origins[helper]
+		origins := make([]string, 0, len(visited)+1)
+		for origin := range visited {
+			origins = append(origins, origin)
+		}
+		p.mu.Unlock()
+		if origin := originOf(location); origin != "" {
+			origins = append(origins, origin)
+		}
+		for _, origin := range origins {
+			if err := storage.ClearDataForOrigin(origin, "all").Do(ctx); err != nil {
+				return err
+			}
+		}
+
+		err := storage.ClearCookies().
+			WithBrowserContextID(helper.BrowserContextID()).
+			Do(helper.NewBrowserExecutor(ctx))
+		if err != nil {
+			return err
+		}
+
+		if err = chromedp.Navigate("about:blank").Do(ctx); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		for _, origin := range origins {
+			delete(visited, origin)
+		}
+		p.mu.Unlock()
+		return nil
+	}))
+	if err != nil || helper.session.state == nil {
+		return err
+	}
+	return helper.loadStorage(helper.session.state)
+}
+
+// Close closes idle helpers, leased helpers are closed when they are released
+func (p *BrowserPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.idle)
+	p.mu.Unlock()
+
+	var errs []error
+	for helper := range p.idle {
+		p.mu.Lock()
+		delete(p.origins, helper)
+		p.mu.Unlock()
+		if err := helper.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// BrowserJob is a Job whose Do runs on a helper leased from a BrowserPool
+type BrowserJob interface {
+	Prev() ([]Arg, bool)
+	Do(h *CdpHelper, arg Arg) bool
+	Post(args *[]Arg)
+}
+
+// Job adapts job to be run by Scheduler, every Do leases a helper from p
+func (p *BrowserPool) Job(job BrowserJob) Job {
+	return &pooledJob{
+		pool: p,
+		job:  job,
+	}
+}
+
+type pooledJob struct {
+	pool *BrowserPool
+	job  BrowserJob
+}
+
+func (j *pooledJob) Prev() ([]Arg, bool) {
+	return j.job.Prev()
+}
+
+func (j *pooledJob) Do(arg Arg) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), j.pool.AcquireTimeout)
+	defer cancel()
+	helper, err := j.pool.Acquire(ctx)
+	if err != nil {
+		return false
+	}
+	defer j.pool.Release(helper)
+
+	return j.job.Do(helper, arg)
+}
+
+func (j *pooledJob) Post(args *[]Arg) {
+	j.job.Post(args)
+}
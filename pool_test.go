@@ -0,0 +1,102 @@
+package cdp_helper
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testBrowserJob struct {
+	url  string
+	done atomic.Int32
+}
+
+func (j *testBrowserJob) Prev() ([]Arg, bool) {
+	var args []Arg
+	for i := 0; i < 6; i++ {
+		args = append(args, Arg{"id": i})
+	}
+	return args, true
+}
+
+func (j *testBrowserJob) Do(h *CdpHelper, arg Arg) bool {
+	if err := h.Navigate(fmt.Sprintf("%s/?id=%v", j.url, arg["id"])); err != nil {
+		return false
+	}
+	text, err := h.NodeTextContent(`body`)
+	if err != nil || text != "ok" {
+		return false
+	}
+	j.done.Add(1)
+	return true
+}
+
+func (j *testBrowserJob) Post(args *[]Arg) {
+}
+
+func TestBrowserPool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	pool, err := NewTabPool(b, 3)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	job := &testBrowserJob{url: server.URL}
+	sch := NewScheduler()
+	sch.Timeout = 30 * time.Second
+	assert.True(t, sch.Schedule(pool.Job(job)))
+	assert.Equal(t, int32(6), job.done.Load())
+
+	h, err := pool.Acquire(context.Background())
+	assert.Nil(t, err)
+	text, err := h.NodeTextContent(`body`)
+	assert.Nil(t, err)
+	assert.Empty(t, text)
+	pool.Release(h)
+}
+
+func TestBrowserPool_reset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer server.Close()
+
+	pool, err := NewBrowserPool(1, WithHeadless(true))
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	h, err := pool.Acquire(context.Background())
+	assert.Nil(t, err)
+	err = h.Navigate(server.URL)
+	assert.Nil(t, err)
+	err = h.Run(chromedp.Evaluate(`localStorage.setItem("token", "abc")`, nil))
+	assert.Nil(t, err)
+	// the last page is of another origin
+	err = h.Navigate(strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+	assert.Nil(t, err)
+	pool.Release(h)
+
+	h, err = pool.Acquire(context.Background())
+	assert.Nil(t, err)
+	err = h.Navigate(server.URL)
+	assert.Nil(t, err)
+	var token any
+	err = h.Run(chromedp.Evaluate(`localStorage.getItem("token")`, &token))
+	assert.Nil(t, err)
+	assert.Nil(t, token)
+	pool.Release(h)
+}
@@ -0,0 +1,201 @@
+package cdp_helper
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"regexp"
+	"sync"
+)
+
+// ResponseWatcher selects the responses to watch, a zero field matches anything
+type ResponseWatcher struct {
+	Method string                  // request method, e.g. "POST"
+	URL    *regexp.Regexp          // matched against the request url
+	Types  []network.ResourceType  // e.g. network.ResourceTypeXHR, network.ResourceTypeFetch
+	Status func(status int64) bool // matched against the response status
+}
+
+// NetworkResponse is a response matched by a ResponseWatcher
+type NetworkResponse struct {
+	RequestID     network.RequestID
+	Method        string
+	URL           string
+	Type          network.ResourceType
+	Status        int64
+	StatusText    string
+	Headers       network.Headers
+	MimeType      string
+	Body          []byte
+	Base64Encoded bool  // whether chrome sent the body base64 encoded, Body is always decoded
+	Redirect      bool  // whether the response is a redirect, which has no body
+	Err           error // set when the request failed or the body couldn't be read
+}
+
+func (w *ResponseWatcher) matchRequest(request *network.Request, resourceType network.ResourceType) bool {
+	if w.Method != "" && w.Method != request.Method {
+		return false
+	}
+	if w.URL != nil && !w.URL.MatchString(request.URL) {
+		return false
+	}
+	if len(w.Types) == 0 {
+		return true
+	}
+	for _, t := range w.Types {
+		if t == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *ResponseWatcher) matchStatus(status int64) bool {
+	return w.Status == nil || w.Status(status)
+}
+
+// WatchResponses streams every response of the current tab matched by watcher, in the order they completed.
+// Failed requests are sent with Err whatever watcher.Status is.
+// The returned channel is closed after ctx is done or the tab is closed.
+func (h *CdpHelper) WatchResponses(ctx context.Context, watcher ResponseWatcher) <-chan *NetworkResponse {
+	ch := make(chan *NetworkResponse, 16)
+	// listening needs the chromedp context of the tab, ctx may be any context
+	watchCtx, stop := mergeDone(h.Current.Context, ctx)
+
+	type completed struct {
+		resp     *NetworkResponse
+		readBody bool
+	}
+	var mu sync.Mutex
+	var queue []completed
+	queued := make(chan struct{}, 1)
+	matched := make(map[network.RequestID]*NetworkResponse)
+
+	// send queues resp for the goroutine below, which reads the bodies and sends in order
+	send := func(resp *NetworkResponse, readBody bool) {
+		mu.Lock()
+		queue = append(queue, completed{resp: resp, readBody: readBody})
+		mu.Unlock()
+		select {
+		case queued <- struct{}{}:
+		default:
+		}
+	}
+
+	chromedp.ListenTarget(watchCtx, func(ev any) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			mu.Lock()
+			prev, ok := matched[ev.RequestID]
+			delete(matched, ev.RequestID)
+			mu.Unlock()
+			if ok && ev.RedirectResponse != nil && watcher.matchStatus(ev.RedirectResponse.Status) {
+				fillResponse(prev, ev.RedirectResponse)
+				prev.Redirect = true
+				send(prev, false)
+			}
+			if watcher.matchRequest(ev.Request, ev.Type) {
+				mu.Lock()
+				matched[ev.RequestID] = &NetworkResponse{
+					RequestID: ev.RequestID,
+					Method:    ev.Request.Method,
+					URL:       ev.Request.URL,
+					Type:      ev.Type,
+				}
+				mu.Unlock()
+			}
+		case *network.EventResponseReceived:
+			mu.Lock()
+			defer mu.Unlock()
+			resp, ok := matched[ev.RequestID]
+			if !ok {
+				return
+			}
+			if !watcher.matchStatus(ev.Response.Status) {
+				delete(matched, ev.RequestID)
+				return
+			}
+			fillResponse(resp, ev.Response)
+		case *network.EventLoadingFinished:
+			mu.Lock()
+			resp, ok := matched[ev.RequestID]
+			delete(matched, ev.RequestID)
+			mu.Unlock()
+			if ok && resp.Status != 0 {
+				send(resp, true)
+			}
+		case *network.EventLoadingFailed:
+			mu.Lock()
+			resp, ok := matched[ev.RequestID]
+			delete(matched, ev.RequestID)
+			mu.Unlock()
+			if ok {
+				resp.Err = errors.New(ev.ErrorText)
+				send(resp, false)
+			}
+		}
+	})
+
+	go func() {
+		defer close(ch)
+		defer stop()
+		for {
+			mu.Lock()
+			if len(queue) == 0 {
+				mu.Unlock()
+				select {
+				case <-queued:
+					continue
+				case <-watchCtx.Done():
+					return
+				}
+			}
+			next := queue[0]
+			queue = queue[1:]
+			mu.Unlock()
+
+			if next.readBody {
+				timeoutCtx, timeoutCancel := context.WithTimeout(watchCtx, h.Timeout)
+				next.resp.Body, next.resp.Base64Encoded, next.resp.Err = responseBody(h.NewTargetExecutor(timeoutCtx), next.resp.RequestID)
+				timeoutCancel()
+			}
+			select {
+			case ch <- next.resp:
+			case <-watchCtx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+func fillResponse(resp *NetworkResponse, response *network.Response) {
+	resp.URL = response.URL
+	resp.Status = response.Status
+	resp.StatusText = response.StatusText
+	resp.Headers = response.Headers
+	resp.MimeType = response.MimeType
+}
+
+// responseBody returns the decoded body of request id and whether it was base64 encoded
+func responseBody(executor context.Context, id network.RequestID) ([]byte, bool, error) {
+	var res network.GetResponseBodyReturns
+	err := cdp.Execute(executor, network.CommandGetResponseBody, network.GetResponseBody(id), &res)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !res.Base64encoded {
+		return []byte(res.Body), false, nil
+	}
+
+	body, err := base64.StdEncoding.DecodeString(res.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	return body, true, nil
+}
@@ -0,0 +1,79 @@
+package cdp_helper
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestResponseWatcher_match(t *testing.T) {
+	watcher := ResponseWatcher{
+		Method: "POST",
+		URL:    regexp.MustCompile(`/api/`),
+		Types:  []network.ResourceType{network.ResourceTypeFetch},
+		Status: func(status int64) bool { return status == 200 },
+	}
+	assert.True(t, watcher.matchRequest(&network.Request{Method: "POST", URL: "https://a.com/api/x"}, network.ResourceTypeFetch))
+	assert.False(t, watcher.matchRequest(&network.Request{Method: "GET", URL: "https://a.com/api/x"}, network.ResourceTypeFetch))
+	assert.False(t, watcher.matchRequest(&network.Request{Method: "POST", URL: "https://a.com/x"}, network.ResourceTypeFetch))
+	assert.False(t, watcher.matchRequest(&network.Request{Method: "POST", URL: "https://a.com/api/x"}, network.ResourceTypeXHR))
+	assert.True(t, watcher.matchStatus(200))
+	assert.False(t, watcher.matchStatus(404))
+	assert.True(t, (&ResponseWatcher{}).matchRequest(&network.Request{Method: "GET"}, network.ResourceTypeImage))
+}
+
+func TestCdpHelper_WatchResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/a", "/api/b":
+			w.Header().Set("X-Api", r.URL.Path)
+			_, _ = w.Write([]byte(r.URL.Path))
+		case "/api/png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><script>
+fetch("/api/a").then(() => fetch("/api/b")).then(() => fetch("/api/png"))
+</script></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate("about:blank")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	watchCtx, watchCancel := context.WithCancel(context.Background())
+	responses := b.WatchResponses(watchCtx, ResponseWatcher{
+		URL:   regexp.MustCompile(`/api/`),
+		Types: []network.ResourceType{network.ResourceTypeFetch},
+	})
+	err = b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	var bodies []string
+	for len(bodies) < 3 {
+		select {
+		case resp := <-responses:
+			assert.Nil(t, resp.Err)
+			assert.Equal(t, int64(200), resp.Status)
+			bodies = append(bodies, string(resp.Body))
+		case <-ctx.Done():
+			assert.FailNow(t, "timeout")
+		}
+	}
+	assert.Equal(t, []string{"/api/a", "/api/b", "\x89PNG"}, bodies)
+
+	watchCancel()
+	_, ok := <-responses
+	assert.False(t, ok)
+}
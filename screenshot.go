@@ -0,0 +1,200 @@
+package cdp_helper
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"io"
+	"math"
+)
+
+// ScreenshotOption configures Screenshot and ScreenshotTo
+type ScreenshotOption func(*screenshotConfig)
+
+type screenshotConfig struct {
+	fullPage bool
+	format   page.CaptureScreenshotFormat
+	quality  int64
+	// clip returns the captured area in page coordinates, nil captures the viewport or the full page
+	clip func(ctx context.Context) (*page.Viewport, error)
+}
+
+// WithFullPage captures the whole page instead of the viewport
+func WithFullPage() ScreenshotOption {
+	return func(config *screenshotConfig) {
+		config.fullPage = true
+	}
+}
+
+// WithElement captures the first visible element matching sel, it replaces a previous WithNode or WithClip
+func WithElement(sel any, opts ...chromedp.QueryOption) ScreenshotOption {
+	return func(config *screenshotConfig) {
+		config.clip = func(ctx context.Context) (*page.Viewport, error) {
+			var nodes []*cdp.Node
+			if err := chromedp.Nodes(sel, &nodes, append(opts, chromedp.NodeVisible)...).Do(ctx); err != nil {
+				return nil, err
+			}
+			if len(nodes) == 0 {
+				return nil, fmt.Errorf("selector %q: %w", sel, ErrNotFound)
+			}
+			return nodeClip(ctx, nodes[0])
+		}
+	}
+}
+
+// WithNode captures node, it replaces a previous WithElement or WithClip
+func WithNode(node *cdp.Node) ScreenshotOption {
+	return func(config *screenshotConfig) {
+		config.clip = func(ctx context.Context) (*page.Viewport, error) {
+			return nodeClip(ctx, node)
+		}
+	}
+}
+
+// WithClip captures the rectangle in CSS pixels relative to the top left of the page,
+// it replaces a previous WithElement or WithNode
+func WithClip(x, y, width, height float64) ScreenshotOption {
+	return func(config *screenshotConfig) {
+		config.clip = func(ctx context.Context) (*page.Viewport, error) {
+			return &page.Viewport{X: x, Y: y, Width: width, Height: height, Scale: 1}, nil
+		}
+	}
+}
+
+// WithJPEG encodes the screenshot as JPEG, quality ranges from 0 to 100
+func WithJPEG(quality int64) ScreenshotOption {
+	return func(config *screenshotConfig) {
+		config.format = page.CaptureScreenshotFormatJpeg
+		config.quality = quality
+	}
+}
+
+// WithWebP encodes the screenshot as WebP, quality ranges from 0 to 100
+func WithWebP(quality int64) ScreenshotOption {
+	return func(config *screenshotConfig) {
+		config.format = page.CaptureScreenshotFormatWebp
+		config.quality = quality
+	}
+}
+
+// nodeClip scrolls node into view and returns its border box in page coordinates
+func nodeClip(ctx context.Context, node *cdp.Node) (*page.Viewport, error) {
+	scroll := dom.ScrollIntoViewIfNeeded()
+	box := dom.GetBoxModel()
+	if node.NodeID != 0 {
+		scroll = scroll.WithNodeID(node.NodeID)
+		box = box.WithNodeID(node.NodeID)
+	} else {
+		scroll = scroll.WithBackendNodeID(node.BackendNodeID)
+		box = box.WithBackendNodeID(node.BackendNodeID)
+	}
+
+	if err := scroll.Do(ctx); err != nil {
+		return nil, err
+	}
+	model, err := box.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, _, visual, _, err := page.GetLayoutMetrics().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return quadClip(model.Border, visual.PageX, visual.PageY), nil
+}
+
+// quadClip returns the bounding rectangle of a quad in viewport coordinates, moved by the scroll offset
+// and aligned to whole pixels
+func quadClip(quad dom.Quad, scrollX, scrollY float64) *page.Viewport {
+	left, top, right, bottom := quadBounds(quad)
+	x, y := math.Round(left+scrollX), math.Round(top+scrollY)
+	return &page.Viewport{
+		X:      x,
+		Y:      y,
+		Width:  math.Round(right+scrollX) - x,
+		Height: math.Round(bottom+scrollY) - y,
+		Scale:  1,
+	}
+}
+
+// quadBounds returns the edges of the bounding rectangle of a quad
+func quadBounds(quad dom.Quad) (left, top, right, bottom float64) {
+	left, top = math.Inf(1), math.Inf(1)
+	right, bottom = math.Inf(-1), math.Inf(-1)
+	for i := 0; i+1 < len(quad); i += 2 {
+		left = math.Min(left, quad[i])
+		right = math.Max(right, quad[i])
+		top = math.Min(top, quad[i+1])
+		bottom = math.Max(bottom, quad[i+1])
+	}
+	return left, top, right, bottom
+}
+
+// Screenshot captures the viewport of the current tab as PNG, opts select the area and the format.
+// It returns nil without capturing anything when EnableScreenshot is false.
+func (h *CdpHelper) Screenshot(opts ...ScreenshotOption) ([]byte, error) {
+	if !h.EnableScreenshot {
+		return nil, nil
+	}
+
+	config := &screenshotConfig{format: page.CaptureScreenshotFormatPng}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(h.Current.Context, h.Timeout)
+	defer timeoutCancel()
+
+	var data []byte
+	err := chromedp.Run(timeoutCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		params := page.CaptureScreenshot().
+			WithFormat(config.format).
+			WithFromSurface(true)
+		if config.format != page.CaptureScreenshotFormatPng {
+			params = params.WithQuality(config.quality)
+		}
+		if config.fullPage && config.clip == nil {
+			// captureBeyondViewport alone still captures the viewport size
+			_, _, _, _, _, contentSize, err := page.GetLayoutMetrics().Do(ctx)
+			if err != nil {
+				return err
+			}
+			params = params.WithCaptureBeyondViewport(true).WithClip(&page.Viewport{
+				Width:  contentSize.Width,
+				Height: contentSize.Height,
+				Scale:  1,
+			})
+		}
+		if config.clip != nil {
+			clip, err := config.clip(ctx)
+			if err != nil {
+				return err
+			}
+			params = params.WithClip(clip).WithCaptureBeyondViewport(true)
+		}
+
+		var err error
+		data, err = params.Do(ctx)
+		return err
+	}))
+	if err != nil {
+		// not fail, saving the artifacts takes a screenshot too
+		return nil, h.classify(err)
+	}
+
+	return data, nil
+}
+
+// ScreenshotTo writes the screenshot taken with opts to w, see Screenshot
+func (h *CdpHelper) ScreenshotTo(w io.Writer, opts ...ScreenshotOption) error {
+	data, err := h.Screenshot(opts...)
+	if err != nil || data == nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,66 @@
+package cdp_helper
+
+import (
+	"bytes"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQuadClip(t *testing.T) {
+	quad := dom.Quad{10.4, 20.6, 110.4, 20.6, 110.4, 70.2, 10.4, 70.2}
+	clip := quadClip(quad, 0, 300)
+	assert.Equal(t, &page.Viewport{X: 10, Y: 321, Width: 100, Height: 49, Scale: 1}, clip)
+}
+
+func TestCdpHelper_Screenshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body style="margin:0;height:3000px">` +
+			`<div id="box" style="position:absolute;top:2000px;left:50px;width:120px;height:80px;background:red"></div>` +
+			`</body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	decode := func(data []byte) (image.Config, string) {
+		config, format, err := image.DecodeConfig(bytes.NewReader(data))
+		assert.Nil(t, err)
+		return config, format
+	}
+
+	data, err := b.Screenshot(WithElement(`#box`))
+	assert.Nil(t, err)
+	config, format := decode(data)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, 120, config.Width)
+	assert.Equal(t, 80, config.Height)
+
+	data, err = b.Screenshot(WithFullPage(), WithJPEG(80))
+	assert.Nil(t, err)
+	config, format = decode(data)
+	assert.Equal(t, "jpeg", format)
+	assert.GreaterOrEqual(t, config.Height, 3000)
+
+	var buf bytes.Buffer
+	err = b.ScreenshotTo(&buf, WithClip(0, 0, 40, 30))
+	assert.Nil(t, err)
+	config, _ = decode(buf.Bytes())
+	assert.Equal(t, 40, config.Width)
+	assert.Equal(t, 30, config.Height)
+
+	b.EnableScreenshot = false
+	data, err = b.Screenshot(WithFullPage())
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+}
@@ -0,0 +1,129 @@
+package cdp_helper
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/chromedp"
+	"strings"
+)
+
+// shadowSeparator splits a selector like "my-app >>> settings-panel >>> input", every part is a css selector
+// matched inside the open shadow roots of the nodes the previous part matched
+const shadowSeparator = ">>>"
+
+// isShadowSelector tells whether sel pierces shadow roots
+func isShadowSelector(sel any) bool {
+	s, ok := sel.(string)
+	return ok && strings.Contains(s, shadowSeparator)
+}
+
+// queryOpts scopes the queries of a frame helper to the frame document, and selects the nodes of a shadow
+// piercing sel. BySearch ignores FromNode, a frame query is a searchFrame unless opts pick another way.
+// A shadow piercing sel is always matched by queryShadow, whatever way opts pick.
+func (h *CdpHelper) queryOpts(sel any, opts []chromedp.QueryOption) []chromedp.QueryOption {
+	var scope []chromedp.QueryOption
+	if h.frame != nil && h.frame.document != nil {
+		scope = append(scope, chromedp.FromNode(h.frame.document))
+		if s, ok := sel.(string); ok {
+			scope = append(scope, chromedp.ByFunc(func(ctx context.Context, n *cdp.Node) ([]cdp.NodeID, error) {
+				return searchFrame(ctx, n.NodeID, s)
+			}))
+		}
+	}
+	if !isShadowSelector(sel) {
+		if len(scope) == 0 {
+			return opts
+		}
+		return append(scope, opts...)
+	}
+	// the last By option wins
+	return append(append(scope, opts...), chromedp.ByFunc(func(ctx context.Context, n *cdp.Node) ([]cdp.NodeID, error) {
+		return queryShadow(ctx, n.NodeID, sel.(string))
+	}))
+}
+
+// queryShadow returns the nodes below root matching the shadow piercing selector sel, in the order of the hosts.
+// The first part is matched in the light DOM of root, then in its open shadow root when root is a shadow host.
+func queryShadow(ctx context.Context, root cdp.NodeID, sel string) ([]cdp.NodeID, error) {
+	scopes := []cdp.NodeID{root}
+	for i, part := range strings.Split(sel, shadowSeparator) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("empty css selector in %q", sel)
+		}
+		roots, err := openShadowRoots(ctx, scopes)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			scopes = append(scopes, roots...)
+		} else {
+			scopes = roots
+		}
+
+		var matches []cdp.NodeID
+		for _, scope := range scopes {
+			nodeIDs, err := dom.QuerySelectorAll(scope, part).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, nodeIDs...)
+		}
+		scopes = matches
+	}
+	return scopes, nil
+}
+
+// openShadowRoots returns the NodeIDs of the open shadow roots of hosts, the hosts without one are skipped
+func openShadowRoots(ctx context.Context, hosts []cdp.NodeID) ([]cdp.NodeID, error) {
+	var backendIDs []cdp.BackendNodeID
+	for _, host := range hosts {
+		node, err := dom.DescribeNode().WithNodeID(host).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, root := range node.ShadowRoots {
+			if root.ShadowRootType == cdp.ShadowRootTypeOpen {
+				backendIDs = append(backendIDs, root.BackendNodeID)
+			}
+		}
+	}
+	if len(backendIDs) == 0 {
+		return nil, nil
+	}
+
+	nodeIDs, err := dom.PushNodesByBackendIDsToFrontend(backendIDs).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roots := nodeIDs[:0]
+	for _, nodeID := range nodeIDs {
+		if nodeID != cdp.EmptyNodeID {
+			roots = append(roots, nodeID)
+		}
+	}
+	return roots, nil
+}
+
+// querySelectorAll is dom.QuerySelectorAll accepting shadow piercing selectors
+func querySelectorAll(ctx context.Context, nodeID cdp.NodeID, sel string) ([]cdp.NodeID, error) {
+	if !isShadowSelector(sel) {
+		return dom.QuerySelectorAll(nodeID, sel).Do(ctx)
+	}
+	return queryShadow(ctx, nodeID, sel)
+}
+
+// querySelector is dom.QuerySelector accepting shadow piercing selectors, it returns cdp.EmptyNodeID
+// when nothing matches
+func querySelector(ctx context.Context, nodeID cdp.NodeID, sel string) (cdp.NodeID, error) {
+	if !isShadowSelector(sel) {
+		return dom.QuerySelector(nodeID, sel).Do(ctx)
+	}
+	nodeIDs, err := queryShadow(ctx, nodeID, sel)
+	if err != nil || len(nodeIDs) == 0 {
+		return cdp.EmptyNodeID, err
+	}
+	return nodeIDs[0], nil
+}
@@ -0,0 +1,90 @@
+package cdp_helper
+
+import (
+	"errors"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsShadowSelector(t *testing.T) {
+	assert.True(t, isShadowSelector(`my-app >>> input`))
+	assert.False(t, isShadowSelector(`div > input`))
+	assert.False(t, isShadowSelector([]int{1}))
+
+	h := &CdpHelper{}
+	assert.Len(t, h.queryOpts(`my-app >>> input`, nil), 1)
+}
+
+func TestCdpHelper_shadowSelectors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><my-app></my-app><secret-box></secret-box><script>
+			customElements.define("login-form", class extends HTMLElement {
+				connectedCallback() {
+					this.attachShadow({mode: "open"}).innerHTML = '<input name="user" title="user name">' +
+						'<button onclick="this.getRootNode().querySelector(\'span\').textContent = this.getRootNode().querySelector(\'input\').value">go</button>' +
+						'<span class="status"></span>';
+				}
+			});
+			customElements.define("my-app", class extends HTMLElement {
+				connectedCallback() {
+					this.attachShadow({mode: "open"}).innerHTML = '<section><login-form></login-form></section>';
+				}
+			});
+			customElements.define("secret-box", class extends HTMLElement {
+				connectedCallback() {
+					this.attachShadow({mode: "closed"}).innerHTML = '<span>hidden</span>';
+				}
+			});
+		</script></body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	err = b.SendKeys(`my-app >>> login-form >>> input`, "gopher")
+	assert.Nil(t, err)
+	err = b.Click(`my-app >>> section login-form >>> button`)
+	assert.Nil(t, err)
+	text, err := b.NodeTextContent(`my-app >>> login-form >>> span.status`)
+	assert.Nil(t, err)
+	assert.Equal(t, "gopher", text)
+	attributes, err := b.Attributes(`my-app >>> login-form >>> input`)
+	assert.Nil(t, err)
+	assert.Equal(t, "user name", attributes["title"])
+
+	text, err = b.NodeTextContent(`my-app >>> login-form >>> span.status`, chromedp.ByQuery)
+	assert.Nil(t, err)
+	assert.Equal(t, "gopher", text)
+
+	// my-app is a shadow host, login-form is in its shadow root
+	nodes, err := b.Nodes(`my-app`)
+	assert.Nil(t, err)
+	text, err = b.ChildNodeTextContent(nodes[0], `login-form >>> span`)
+	assert.Nil(t, err)
+	assert.Equal(t, "gopher", text)
+	_, ok := b.HasChildNode(nodes[0], `login-form >>> button`)
+	assert.True(t, ok)
+
+	section, err := b.Query(`my-app >>> section`)
+	assert.Nil(t, err)
+	input, err := section.Query(`login-form >>> input`)
+	assert.Nil(t, err)
+	name, _, err := input.Attr("name")
+	assert.Nil(t, err)
+	assert.Equal(t, "user", name)
+
+	elements, err := b.QueryAll(`secret-box >>> span`)
+	assert.Nil(t, err)
+	assert.Empty(t, elements)
+	_, _, err = b.ChildNode(b.Current.Context, nodes[0].NodeID, `>>> span`)
+	assert.NotNil(t, err)
+	_, err = section.Query(`login-form >>> select`)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,260 @@
+package cdp_helper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"io"
+	"net/url"
+	"time"
+)
+
+// State is the cookies and web storage of a browser context, see SaveState and LoadState
+type State struct {
+	Cookies []*network.Cookie `json:"cookies"`
+	Origins []*OriginState    `json:"origins"`
+}
+
+// OriginState is the web storage of an origin like "https://example.com"
+type OriginState struct {
+	Origin         string            `json:"origin"`
+	LocalStorage   map[string]string `json:"localStorage,omitempty"`
+	SessionStorage map[string]string `json:"sessionStorage,omitempty"`
+}
+
+// ReadState decodes a state written by SaveState
+func ReadState(r io.Reader) (*State, error) {
+	var state State
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+const storageDumpJS = `(() => {
+	try {
+		const dump = storage => Object.fromEntries(Object.keys(storage).map(key => [key, storage.getItem(key)]));
+		return {origin: location.origin, localStorage: dump(localStorage), sessionStorage: dump(sessionStorage)};
+	} catch (e) {
+		return {origin: location.origin};
+	}
+})()`
+
+const localStorageLoadJS = `(items => {
+	for (const [key, value] of Object.entries(items)) {
+		localStorage.setItem(key, value);
+	}
+})(%s)`
+
+// sessionStorageLoadJS fills the session storage of the first page of each origin loaded in the tab
+const sessionStorageLoadJS = `(state => {
+	const items = state[location.origin];
+	if (!items || sessionStorage.length > 0) {
+		return;
+	}
+	for (const [key, value] of Object.entries(items)) {
+		sessionStorage.setItem(key, value);
+	}
+})(%s)`
+
+// originOf returns the origin of an http or https url, or "" for other urls
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// SaveState writes the cookies of the browser context of the current tab and the web storage of origins,
+// default is the origin of the current page, to w as JSON. The session storage can only be read for the
+// origin of the current page, it's per tab.
+func (h *CdpHelper) SaveState(w io.Writer, origins ...string) error {
+	state := &State{}
+	var current OriginState
+	err := h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		state.Cookies, err = storage.GetCookies().
+			WithBrowserContextID(h.BrowserContextID()).
+			Do(h.NewBrowserExecutor(ctx))
+		if err != nil {
+			return err
+		}
+		return chromedp.Evaluate(storageDumpJS, &current).Do(ctx)
+	}))
+	if err != nil {
+		return err
+	}
+
+	if len(origins) == 0 && current.Origin != "null" {
+		origins = []string{current.Origin}
+	}
+
+	var others []string
+	for _, origin := range origins {
+		origin = originOf(origin)
+		if origin == "" {
+			continue
+		}
+		if origin == current.Origin {
+			state.Origins = append(state.Origins, &current)
+			continue
+		}
+		others = append(others, origin)
+	}
+
+	err = h.withOriginTab(others, func(tab *CdpHelper, origin string) error {
+		var dump OriginState
+		if err := tab.Run(chromedp.Evaluate(storageDumpJS, &dump)); err != nil {
+			return err
+		}
+		// a new tab has no session storage
+		dump.SessionStorage = nil
+		state.Origins = append(state.Origins, &dump)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(state)
+}
+
+// LoadState restores a state written by SaveState into the browser context of the current tab.
+// The session storage is restored into the first page of each origin the current tab loads afterwards.
+func (h *CdpHelper) LoadState(r io.Reader) error {
+	state, err := ReadState(r)
+	if err != nil {
+		return err
+	}
+	return h.applyState(state)
+}
+
+func (h *CdpHelper) applyState(state *State) error {
+	if err := h.loadStorage(state); err != nil {
+		return err
+	}
+
+	sessionStorage := make(map[string]map[string]string)
+	for _, origin := range state.Origins {
+		if len(origin.SessionStorage) > 0 {
+			sessionStorage[origin.Origin] = origin.SessionStorage
+		}
+	}
+	if len(sessionStorage) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(sessionStorage)
+	if err != nil {
+		return err
+	}
+	return h.Run(chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(fmt.Sprintf(sessionStorageLoadJS, data)).Do(ctx)
+		return err
+	}))
+}
+
+// loadStorage sets the cookies and the local storage of state
+func (h *CdpHelper) loadStorage(state *State) error {
+	cookies := make([]*network.CookieParam, 0, len(state.Cookies))
+	for _, c := range state.Cookies {
+		param := &network.CookieParam{
+			Name:         c.Name,
+			Value:        c.Value,
+			Domain:       c.Domain,
+			Path:         c.Path,
+			Secure:       c.Secure,
+			HTTPOnly:     c.HTTPOnly,
+			SameSite:     c.SameSite,
+			Priority:     c.Priority,
+			SameParty:    c.SameParty,
+			SourceScheme: c.SourceScheme,
+			SourcePort:   c.SourcePort,
+			PartitionKey: c.PartitionKey,
+		}
+		if !c.Session {
+			expires := cdp.TimeSinceEpoch(time.Unix(0, int64(c.Expires*float64(time.Second))))
+			param.Expires = &expires
+		}
+		cookies = append(cookies, param)
+	}
+
+	if len(cookies) > 0 {
+		err := h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+			return storage.SetCookies(cookies).
+				WithBrowserContextID(h.BrowserContextID()).
+				Do(h.NewBrowserExecutor(ctx))
+		}))
+		if err != nil {
+			return err
+		}
+	}
+
+	var origins []string
+	localStorage := make(map[string]map[string]string)
+	for _, origin := range state.Origins {
+		if len(origin.LocalStorage) > 0 {
+			origins = append(origins, origin.Origin)
+			localStorage[origin.Origin] = origin.LocalStorage
+		}
+	}
+	return h.withOriginTab(origins, func(tab *CdpHelper, origin string) error {
+		data, err := json.Marshal(localStorage[origin])
+		if err != nil {
+			return err
+		}
+		return tab.Run(chromedp.Evaluate(fmt.Sprintf(localStorageLoadJS, data), nil))
+	})
+}
+
+// withOriginTab calls fn for each origin with a temporary tab of the browser context of the current tab
+// showing an empty page of the origin, the pages are served by interception so no server is contacted
+func (h *CdpHelper) withOriginTab(origins []string, fn func(tab *CdpHelper, origin string) error) error {
+	if len(origins) == 0 {
+		return nil
+	}
+
+	var id target.ID
+	err := h.RunWithTimeout(h.Timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		id, err = target.CreateTarget("about:blank").
+			WithBrowserContextID(h.BrowserContextID()).
+			Do(h.NewBrowserExecutor(ctx))
+		return err
+	}))
+	if err != nil {
+		return err
+	}
+
+	tab, err := h.Tabs().Attach(id)
+	if err != nil {
+		return err
+	}
+	defer tab.Close()
+
+	_, err = tab.Intercept("*", func(req *InterceptedRequest) InterceptAction {
+		return Fulfill(200, map[string]string{"Content-Type": "text/html"}, []byte("<html></html>"))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, origin := range origins {
+		if err = tab.Navigate(origin + "/"); err != nil {
+			return err
+		}
+		if err = fn(tab, origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
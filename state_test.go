@@ -0,0 +1,86 @@
+package cdp_helper
+
+import (
+	"bytes"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOriginOf(t *testing.T) {
+	assert.Equal(t, "https://example.com", originOf("https://example.com/login?next=/"))
+	assert.Equal(t, "http://127.0.0.1:8080", originOf("http://127.0.0.1:8080"))
+	assert.Equal(t, "", originOf("about:blank"))
+	assert.Equal(t, "", originOf("null"))
+}
+
+func TestReadState(t *testing.T) {
+	state, err := ReadState(strings.NewReader(`{
+		"cookies": [{"name": "sid", "value": "1", "domain": "example.com", "path": "/", "expires": -1, "session": true}],
+		"origins": [{"origin": "https://example.com", "localStorage": {"token": "abc"}}]
+	}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "sid", state.Cookies[0].Name)
+	assert.True(t, state.Cookies[0].Session)
+	assert.Equal(t, "abc", state.Origins[0].LocalStorage["token"])
+
+	_, err = ReadState(strings.NewReader(`{`))
+	assert.NotNil(t, err)
+}
+
+func TestCdpHelper_SaveState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s3cret", Path: "/", HttpOnly: true})
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><script>
+			if (location.pathname === "/login") {
+				localStorage.setItem("token", "abc");
+				sessionStorage.setItem("step", "2");
+			}
+		</script></body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL + "/login")
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = b.SaveState(&buf)
+	assert.Nil(t, err)
+	state, err := ReadState(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	assert.Len(t, state.Origins, 1)
+	assert.Equal(t, "abc", state.Origins[0].LocalStorage["token"])
+	assert.Equal(t, "2", state.Origins[0].SessionStorage["step"])
+
+	restored := NewBrowserWithOptions(WithState(state))
+	defer restored.Close()
+	err = restored.Navigate(server.URL + "/home")
+	assert.Nil(t, err)
+
+	var token, step string
+	err = restored.Run(
+		chromedp.Evaluate(`localStorage.getItem("token")`, &token),
+		chromedp.Evaluate(`sessionStorage.getItem("step")`, &step),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", token)
+	assert.Equal(t, "2", step)
+
+	other := NewBrowser(true)
+	defer other.Close()
+	err = other.LoadState(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	var saved bytes.Buffer
+	err = other.SaveState(&saved, server.URL)
+	assert.Nil(t, err)
+	assert.Contains(t, saved.String(), `"s3cret"`)
+	assert.Contains(t, saved.String(), `"token": "abc"`)
+}
@@ -0,0 +1,216 @@
+package cdp_helper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Table is the text of an HTML table, the cells spanning several rows or columns are repeated in each of them
+type Table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// tableJS returns the text grid of the table matching the selector, or of the first table inside it,
+// and the number of header rows
+const tableJS = `((sel) => {
+	let table = document.querySelector(sel);
+	if (table && table.tagName !== "TABLE") {
+		table = table.querySelector("table");
+	}
+	if (!table) {
+		return null;
+	}
+
+	const text = cell => (cell.innerText || cell.textContent || "").replace(/\s+/g, " ").trim();
+	const rows = Array.from(table.rows);
+	const grid = rows.map(() => []);
+	rows.forEach((row, r) => {
+		let c = 0;
+		for (const cell of row.cells) {
+			while (grid[r][c] !== undefined) {
+				c++;
+			}
+			const value = text(cell);
+			const rowSpan = cell.rowSpan === 0 ? rows.length - r : Math.max(1, cell.rowSpan);
+			const colSpan = Math.max(1, cell.colSpan);
+			for (let i = 0; i < rowSpan && r + i < rows.length; i++) {
+				for (let j = 0; j < colSpan; j++) {
+					grid[r + i][c + j] = value;
+				}
+			}
+			c += colSpan;
+		}
+	});
+
+	let head = rows.filter(row => row.parentElement.tagName === "THEAD").length;
+	if (head === 0) {
+		while (head < rows.length && rows[head].cells.length > 0 &&
+			Array.from(rows[head].cells).every(cell => cell.tagName === "TH")) {
+			head++;
+		}
+	}
+
+	const width = Math.max(0, ...grid.map(row => row.length));
+	return {
+		head: head,
+		grid: grid.map(row => Array.from({length: width}, (_, i) => row[i] === undefined ? "" : row[i])),
+	};
+})(%s)`
+
+type tableGrid struct {
+	Head int        `json:"head"`
+	Grid [][]string `json:"grid"`
+}
+
+// newTable joins the header rows of grid column by column, a header spanning several columns
+// prefixes the headers below it, e.g. "Q1 Jan"
+func newTable(grid *tableGrid) *Table {
+	t := &Table{}
+	head := grid.Head
+	if head > len(grid.Grid) {
+		head = len(grid.Grid)
+	}
+	if head > 0 {
+		t.Headers = make([]string, len(grid.Grid[0]))
+		for i := range t.Headers {
+			var parts []string
+			for _, row := range grid.Grid[:head] {
+				if row[i] != "" && (len(parts) == 0 || parts[len(parts)-1] != row[i]) {
+					parts = append(parts, row[i])
+				}
+			}
+			t.Headers[i] = strings.Join(parts, " ")
+		}
+	}
+	t.Rows = grid.Grid[head:]
+	return t
+}
+
+// Table returns the table matching the css selector sel, or the first table inside it. The header rows are the
+// rows of thead, or the leading rows made of th cells only. Cell text is the rendered text with whitespace collapsed.
+func (h *CdpHelper) Table(sel string) (*Table, error) {
+	data, err := json.Marshal(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	var grid *tableGrid
+	err = h.evaluate(fmt.Sprintf(tableJS, data), &grid)
+	if err != nil {
+		return nil, err
+	}
+	if grid == nil {
+		return nil, fmt.Errorf("table %q: %w", sel, ErrNotFound)
+	}
+	return newTable(grid), nil
+}
+
+// WriteCSV writes the headers, if any, and the rows to w as CSV
+func (t *Table) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if len(t.Headers) > 0 {
+		if err := writer.Write(t.Headers); err != nil {
+			return err
+		}
+	}
+	if err := writer.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// Decode fills v, a pointer to a slice of structs, with one element per row. A field takes the column whose
+// header is its name, case-insensitively, or the name in a tag like `table:"Release Date, layout=2006-01-02"`.
+// A tag option optional allows the column to be missing, and a tag "-" skips the field.
+// The cells are converted like Extract does, an empty cell leaves the zero value and the cells that couldn't be
+// converted are reported as *FieldError.
+func (t *Table) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("decode table: v must be a non-nil pointer to a slice")
+	}
+	dst := rv.Elem()
+	elemType := dst.Type().Elem()
+	if elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("decode table: unsupported type %s", dst.Type())
+	}
+
+	columns := make(map[string]int)
+	for i, header := range t.Headers {
+		key := strings.ToLower(strings.TrimSpace(header))
+		if _, ok := columns[key]; !ok {
+			columns[key] = i
+		}
+	}
+
+	type column struct {
+		field  int
+		index  int
+		name   string
+		layout string
+	}
+	var fields []column
+	for i := 0; i < elemType.NumField(); i++ {
+		sf := elemType.Field(i)
+		tag := sf.Tag.Get("table")
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+
+		name := sf.Name
+		layout := time.RFC3339
+		optional := false
+		parts := strings.Split(tag, ",")
+		if strings.TrimSpace(parts[0]) != "" {
+			name = strings.TrimSpace(parts[0])
+		}
+		for _, option := range parts[1:] {
+			option = strings.TrimSpace(option)
+			switch {
+			case option == "optional":
+				optional = true
+			case strings.HasPrefix(option, "layout="):
+				layout = strings.TrimPrefix(option, "layout=")
+			}
+		}
+
+		index, ok := columns[strings.ToLower(name)]
+		if !ok {
+			if optional {
+				continue
+			}
+			return fmt.Errorf("decode table: column %q: %w", name, ErrNotFound)
+		}
+		fields = append(fields, column{field: i, index: index, name: sf.Name, layout: layout})
+	}
+
+	var errs []error
+	dst.Set(reflect.MakeSlice(dst.Type(), len(t.Rows), len(t.Rows)))
+	for r, row := range t.Rows {
+		elem := dst.Index(r)
+		if elem.Kind() == reflect.Pointer {
+			elem.Set(reflect.New(elemType))
+			elem = elem.Elem()
+		}
+		for _, c := range fields {
+			// an empty cell leaves the zero value
+			if c.index >= len(row) || strings.TrimSpace(row[c.index]) == "" {
+				continue
+			}
+			if err := setValue(elem.Field(c.field), row[c.index], c.layout); err != nil {
+				errs = append(errs, &FieldError{Field: fmt.Sprintf("[%d].%s", r, c.name), Selector: t.Headers[c.index], Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,81 @@
+package cdp_helper
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type tableRow struct {
+	Name     string
+	Jan      int       `table:"Q1 Jan"`
+	Feb      float64   `table:"q1 feb"`
+	Released time.Time `table:"Released, layout=2006-01-02, optional"`
+	Note     string    `table:"-"`
+}
+
+func TestNewTable(t *testing.T) {
+	table := newTable(&tableGrid{Head: 2, Grid: [][]string{
+		{"Name", "Q1", "Q1"},
+		{"Name", "Jan", "Feb"},
+		{"a", "1,000", "2.5"},
+		{"a", "", "x"},
+	}})
+	assert.Equal(t, []string{"Name", "Q1 Jan", "Q1 Feb"}, table.Headers)
+	assert.Len(t, table.Rows, 2)
+
+	var buf bytes.Buffer
+	err := table.WriteCSV(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "Name,Q1 Jan,Q1 Feb\na,\"1,000\",2.5\na,,x\n", buf.String())
+
+	var rows []tableRow
+	err = table.Decode(&rows)
+	var fieldErr *FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "[1].Feb", fieldErr.Field)
+	assert.Equal(t, "Q1 Feb", fieldErr.Selector)
+	assert.Equal(t, []tableRow{{Name: "a", Jan: 1000, Feb: 2.5}, {Name: "a"}}, rows)
+
+	var missing []struct{ Total int }
+	err = table.Decode(&missing)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	empty := newTable(&tableGrid{Grid: [][]string{{"a", "b"}}})
+	assert.Nil(t, empty.Headers)
+	assert.Equal(t, [][]string{{"a", "b"}}, empty.Rows)
+}
+
+func TestCdpHelper_Table(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><div id="report"><table>
+			<thead>
+				<tr><th rowspan="2">Name</th><th colspan="2">Q1</th></tr>
+				<tr><th>Jan</th><th>Feb</th></tr>
+			</thead>
+			<tbody>
+				<tr><td rowspan="2"><b>north</b> <i>east</i></td><td>1</td><td>2</td></tr>
+				<tr><td>3</td><td>4</td></tr>
+			</tbody>
+		</table></div></body></html>`))
+	}))
+	defer server.Close()
+
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate(server.URL)
+	assert.Nil(t, err)
+
+	table, err := b.Table(`#report`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Name", "Q1 Jan", "Q1 Feb"}, table.Headers)
+	assert.Equal(t, [][]string{{"north east", "1", "2"}, {"north east", "3", "4"}}, table.Rows)
+
+	_, err = b.Table(`#missing`)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,308 @@
+package cdp_helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// TabInfo describes an open page target
+type TabInfo struct {
+	ID    target.ID
+	URL   string
+	Title string
+}
+
+// tabRegistry tracks attached tab helpers by target and the tabs opened by pages,
+// it is shared by every helper of a session
+type tabRegistry struct {
+	mu       sync.Mutex
+	attached map[target.ID]*CdpHelper
+	opened   []target.ID
+	notify   chan struct{}
+	discover onceError
+}
+
+func newTabRegistry() *tabRegistry {
+	return &tabRegistry{
+		attached: make(map[target.ID]*CdpHelper),
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+func (r *tabRegistry) get(id target.ID) (*CdpHelper, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tab, ok := r.attached[id]
+	return tab, ok
+}
+
+func (r *tabRegistry) put(id target.ID, tab *CdpHelper) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attached[id] = tab
+	r.opened = removeTargetID(r.opened, id)
+}
+
+func (r *tabRegistry) forget(id target.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attached, id)
+	r.opened = removeTargetID(r.opened, id)
+}
+
+func (r *tabRegistry) pushOpened(id target.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.attached[id]; ok {
+		return
+	}
+	r.opened = append(r.opened, id)
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (r *tabRegistry) popOpened() (target.ID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.opened) == 0 {
+		return "", false
+	}
+	id := r.opened[0]
+	r.opened = r.opened[1:]
+	return id, true
+}
+
+func removeTargetID(ids []target.ID, id target.ID) []target.ID {
+	for i, item := range ids {
+		if item == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+// TabManager lists, attaches, switches and closes the tabs of a browser
+type TabManager struct {
+	helper   *CdpHelper
+	registry *tabRegistry
+}
+
+// Tabs returns the tab manager of h, from now on tabs opened by pages,
+// e.g. target="_blank" links or window.open, are captured and can be got by WaitOpened
+func (h *CdpHelper) Tabs() *TabManager {
+	m := &TabManager{
+		helper:   h,
+		registry: h.session.registry,
+	}
+	_ = m.discover()
+	return m
+}
+
+// TargetID returns the target id of the current tab, it's empty before the tab is attached
+func (h *CdpHelper) TargetID() target.ID {
+	c := chromedp.FromContext(h.Current.Context)
+	if c == nil || c.Target == nil {
+		return ""
+	}
+	return c.Target.TargetID
+}
+
+func (m *TabManager) discover() error {
+	root := m.helper.session.root
+	return m.registry.discover.Do(func() error {
+		chromedp.ListenBrowser(root.Browser.Context, func(ev any) {
+			switch ev := ev.(type) {
+			case *target.EventTargetCreated:
+				if ev.TargetInfo.Type == "page" && ev.TargetInfo.OpenerID != "" {
+					m.registry.pushOpened(ev.TargetInfo.TargetID)
+				}
+			case *target.EventTargetDestroyed:
+				tab, ok := m.registry.get(ev.TargetID)
+				m.registry.forget(ev.TargetID)
+				if ok && !tab.isFirstTab() {
+					// closing waits for the browser, which is sending this event
+					go func() {
+						_ = tab.closeTab()
+					}()
+				}
+			}
+		})
+		err := chromedp.Run(root.Browser.Context, chromedp.ActionFunc(func(ctx context.Context) error {
+			c := chromedp.FromContext(ctx)
+			return target.SetDiscoverTargets(true).Do(cdp.WithExecutor(ctx, c.Browser))
+		}))
+		if err != nil {
+			return err
+		}
+
+		m.registry.put(root.TargetID(), root)
+		return nil
+	})
+}
+
+// List returns all open page targets
+func (m *TabManager) List() ([]TabInfo, error) {
+	if err := m.discover(); err != nil {
+		return nil, err
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(m.helper.Browser.Context, m.helper.Timeout)
+	defer timeoutCancel()
+	infos, err := chromedp.Targets(timeoutCtx)
+	if err != nil {
+		return nil, m.helper.classify(err)
+	}
+
+	var tabs []TabInfo
+	for _, info := range infos {
+		if info.Type != "page" {
+			continue
+		}
+		tabs = append(tabs, TabInfo{
+			ID:    info.TargetID,
+			URL:   info.URL,
+			Title: info.Title,
+		})
+	}
+
+	return tabs, nil
+}
+
+// Attach returns the helper of tab id, attaching to the tab when it isn't attached yet
+func (m *TabManager) Attach(id target.ID) (*CdpHelper, error) {
+	if err := m.discover(); err != nil {
+		return nil, err
+	}
+
+	if tab, ok := m.registry.get(id); ok {
+		return tab, nil
+	}
+
+	root := m.helper.session.root
+	targetContext, targetCancel := chromedp.NewContext(root.Browser.Context, chromedp.WithTargetID(id))
+	if err := chromedp.Run(targetContext); err != nil {
+		targetCancel()
+		return nil, m.helper.classify(err)
+	}
+
+	tab, err := root.newTab(targetContext, targetCancel)
+	if err != nil {
+		return nil, err
+	}
+	m.registry.put(id, tab)
+	return tab, nil
+}
+
+// Find attaches to the first tab whose url matches the regular expression pattern
+func (m *TabManager) Find(pattern string) (*CdpHelper, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	tabs, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tab := range tabs {
+		if re.MatchString(tab.URL) {
+			return m.Attach(tab.ID)
+		}
+	}
+
+	return nil, fmt.Errorf("no tab matches %q: %w", pattern, ErrNotFound)
+}
+
+// Switch attaches to tab id, brings it to front and points the manager's helper to it: Current, and the
+// interception, HAR recording, artifacts and node tracking of the tab. The tab the helper left stays
+// attached and can be switched back to. A tab a helper is switched to can't be closed, switch away first.
+// Switch must not run concurrently with other calls on the manager's helper.
+func (m *TabManager) Switch(id target.ID) error {
+	tab, err := m.Attach(id)
+	if err != nil {
+		return err
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(m.helper.Browser.Context, m.helper.Timeout)
+	defer timeoutCancel()
+	err = chromedp.Run(timeoutCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		c := chromedp.FromContext(ctx)
+		return target.ActivateTarget(id).Do(cdp.WithExecutor(ctx, c.Browser))
+	}))
+	if err != nil {
+		return m.helper.classify(err)
+	}
+
+	if tab == m.helper {
+		return nil
+	}
+	// the tab left keeps a helper of its own when m.helper was its helper
+	if left := m.helper.TargetID(); left != "" {
+		if current, ok := m.registry.get(left); ok && current == m.helper {
+			m.registry.put(left, m.helper.bound())
+		}
+	}
+	m.helper.switchTo(tab)
+	return nil
+}
+
+// switchTo points h to the tab of the tab helper tab
+func (h *CdpHelper) switchTo(tab *CdpHelper) {
+	h.Current = tab.Current
+	h.interceptor = tab.interceptor
+	h.har = tab.har
+	h.artifacts = tab.artifacts
+	h.nodes = tab.nodes
+	h.frames = tab.frames
+	h.inflight = tab.inflight
+	h.crashed = tab.crashed
+}
+
+// Close closes tab id
+func (m *TabManager) Close(id target.ID) error {
+	if tab, ok := m.registry.get(id); ok {
+		if tab.IsRoot() {
+			return errors.New("the root tab can't be closed, use Shutdown instead")
+		}
+		return tab.Close()
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(m.helper.Browser.Context, m.helper.Timeout)
+	defer timeoutCancel()
+	err := chromedp.Run(timeoutCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		c := chromedp.FromContext(ctx)
+		return target.CloseTarget(id).Do(cdp.WithExecutor(ctx, c.Browser))
+	}))
+	return m.helper.classify(err)
+}
+
+// WaitOpened waits for the next tab opened by a page and attaches to it
+func (m *TabManager) WaitOpened(timeout time.Duration) (*CdpHelper, error) {
+	if err := m.discover(); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		if id, ok := m.registry.popOpened(); ok {
+			return m.Attach(id)
+		}
+
+		select {
+		case <-m.registry.notify:
+		case <-timer.C:
+			return nil, &kindError{kind: ErrTimeout, err: context.DeadlineExceeded}
+		}
+	}
+}
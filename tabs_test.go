@@ -0,0 +1,82 @@
+package cdp_helper
+
+import (
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTabRegistry(t *testing.T) {
+	r := newTabRegistry()
+	r.pushOpened("a")
+	r.pushOpened("b")
+	r.put("a", &CdpHelper{})
+	r.pushOpened("a")
+	id, ok := r.popOpened()
+	assert.True(t, ok)
+	assert.Equal(t, target.ID("b"), id)
+	_, ok = r.popOpened()
+	assert.False(t, ok)
+	r.forget("a")
+	_, ok = r.get("a")
+	assert.False(t, ok)
+}
+
+func TestTabManager(t *testing.T) {
+	b := NewBrowser(true)
+	defer b.Close()
+	err := b.Navigate("https://www.baidu.com")
+	assert.Nil(t, err)
+
+	tabs := b.Tabs()
+	err = b.Run(chromedp.Evaluate(`window.open("https://github.com/chromedp/examples"); true`, nil))
+	assert.Nil(t, err)
+	opened, err := tabs.WaitOpened(5 * time.Second)
+	assert.Nil(t, err)
+	err = opened.WaitReady(`body`)
+	assert.Nil(t, err)
+
+	list, err := tabs.List()
+	assert.Nil(t, err)
+	assert.Len(t, list, 2)
+
+	found, err := tabs.Find(`github\.com`)
+	assert.Nil(t, err)
+	assert.Same(t, opened, found)
+
+	root := b.TargetID()
+	err = tabs.Switch(found.TargetID())
+	assert.Nil(t, err)
+	assert.Equal(t, found.TargetID(), b.TargetID())
+	var url string
+	err = b.Run(chromedp.Location(&url))
+	assert.Nil(t, err)
+	assert.Contains(t, url, "github.com")
+	assert.NotNil(t, tabs.Close(found.TargetID()))
+
+	err = tabs.Switch(root)
+	assert.Nil(t, err)
+	assert.Equal(t, root, b.TargetID())
+	assert.NotNil(t, tabs.Close(root))
+
+	err = tabs.Close(found.TargetID())
+	assert.Nil(t, err)
+	list, err = tabs.List()
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	err = b.WaitReady(`body`)
+	assert.Nil(t, err)
+
+	// a tab closed by its page is closed for its helper too
+	err = b.Run(chromedp.Evaluate(`window.open("about:blank"); true`, nil))
+	assert.Nil(t, err)
+	popup, err := tabs.WaitOpened(5 * time.Second)
+	assert.Nil(t, err)
+	err = popup.Run(chromedp.Evaluate(`window.close(); true`, nil))
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return popup.Current.Context.Err() != nil
+	}, 5*time.Second, 100*time.Millisecond)
+}